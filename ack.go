@@ -0,0 +1,175 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// AckQueue is a queue with at-least-once delivery for workers that must not lose items when they fail.
+// Every item received must be acknowledged with the ack function returned by Recv once it has been processed.
+// An item that is not acknowledged within the visibility timeout is delivered again,
+// and calling nack delivers it again right away.
+//
+// An AckQueue must not be copied after first use.
+// Must be constructed with [NewAckQueue]
+type AckQueue[T any] struct {
+	mu         sync.Mutex
+	visibility time.Duration
+	ready      ringBuffer[T]
+	// inFlight holds the deliveries in the order of their deadline since the visibility timeout is the same for all of them.
+	// Settled deliveries are removed lazily.
+	inFlight ringBuffer[*delivery[T]]
+	// unsettled is the number of deliveries in inFlight that are not settled
+	unsettled int
+	closed    bool
+	// wake is closed to wake up the go routines waiting in Recv.
+	// It is nil when none are waiting.
+	wake chan struct{}
+}
+
+type delivery[T any] struct {
+	item     T
+	deadline time.Time
+	// settled is set once the delivery is acknowledged, nacked, or expired.
+	settled bool
+}
+
+// NewAckQueue creates an AckQueue that delivers an item again if it is not acknowledged within visibility.
+// visibility must be positive.
+func NewAckQueue[T any](visibility time.Duration) *AckQueue[T] {
+	if visibility <= 0 {
+		panic("concurrent: visibility timeout must be positive")
+	}
+	return &AckQueue[T]{visibility: visibility}
+}
+
+// Send adds items to the queue.
+// It panics if the AckQueue has been closed.
+func (q *AckQueue[T]) Send(items ...T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		panic("concurrent: send on closed AckQueue")
+	}
+	for _, x := range items {
+		q.ready.push(x)
+	}
+	q.notify()
+}
+
+// Recv removes the oldest item from the queue, waiting for one to be sent or redelivered if the queue is empty.
+// ack marks the item as processed. nack gives the item back to be delivered again right away.
+// Only the first call of ack or nack has an effect, and neither has an effect after the visibility timeout has expired.
+//
+// It returns [ErrClosed] once the AckQueue is closed and all its items have been acknowledged,
+// or the cause of ctx if ctx is done before an item is available.
+func (q *AckQueue[T]) Recv(ctx context.Context) (item T, ack func(), nack func(), err error) {
+	for {
+		q.mu.Lock()
+		now := time.Now()
+		q.expire(now)
+		if x, ok := q.ready.pop(); ok {
+			d := &delivery[T]{item: x, deadline: now.Add(q.visibility)}
+			q.inFlight.push(d)
+			q.unsettled++
+			q.mu.Unlock()
+			return x, func() { q.settle(d, false) }, func() { q.settle(d, true) }, nil
+		}
+		if q.closed && q.unsettled == 0 {
+			q.mu.Unlock()
+			return item, nil, nil, ErrClosed
+		}
+		if q.wake == nil {
+			q.wake = make(chan struct{})
+		}
+		wake := q.wake
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if next, ok := q.nextDeadline(); ok {
+			timer = time.NewTimer(next.Sub(now))
+			expired = timer.C
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-expired:
+		case <-ctx.Done():
+			err = context.Cause(ctx)
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err != nil {
+			return item, nil, nil, err
+		}
+	}
+}
+
+// Close stops the AckQueue from accepting items.
+// Recv continues to deliver the items in the queue and the items that are not acknowledged.
+func (q *AckQueue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.notify()
+}
+
+// Len returns the number of items waiting to be delivered or acknowledged.
+func (q *AckQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.ready.len() + q.unsettled
+}
+
+// settle completes a delivery, giving the item back to the queue if requeue is set.
+func (q *AckQueue[T]) settle(d *delivery[T], requeue bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expire(time.Now())
+	if d.settled {
+		return
+	}
+	d.settled = true
+	q.unsettled--
+	if requeue {
+		q.ready.push(d.item)
+	}
+	q.notify()
+}
+
+// expire gives the items whose visibility timeout has expired back to the queue.
+// It must be called with the lock held.
+func (q *AckQueue[T]) expire(now time.Time) {
+	for q.inFlight.len() > 0 {
+		d := q.inFlight.buf[q.inFlight.head]
+		if !d.settled {
+			if now.Before(d.deadline) {
+				return
+			}
+			d.settled = true
+			q.unsettled--
+			q.ready.push(d.item)
+		}
+		q.inFlight.pop()
+	}
+}
+
+// nextDeadline returns the earliest deadline of the unsettled deliveries.
+// It must be called with the lock held after expire so that the settled deliveries at the front have been removed.
+func (q *AckQueue[T]) nextDeadline() (time.Time, bool) {
+	if q.inFlight.len() == 0 {
+		return time.Time{}, false
+	}
+	return q.inFlight.buf[q.inFlight.head].deadline, true
+}
+
+// notify wakes up the go routines waiting in Recv.
+// It must be called with the lock held.
+func (q *AckQueue[T]) notify() {
+	if q.wake != nil {
+		close(q.wake)
+		q.wake = nil
+	}
+}
@@ -0,0 +1,68 @@
+package concurrent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestAckQueue(t *testing.T) {
+	ctx := context.Background()
+	q := concurrent.NewAckQueue[int](time.Hour)
+	q.Send(1, 2)
+	must.Eq(t, 2, q.Len())
+
+	x, ack, _, err := q.Recv(ctx)
+	must.NoError(t, err)
+	must.Eq(t, 1, x)
+	ack()
+	must.Eq(t, 1, q.Len())
+
+	x, _, nack, err := q.Recv(ctx)
+	must.NoError(t, err)
+	must.Eq(t, 2, x)
+	nack()
+	// only the first call has an effect
+	nack()
+	must.Eq(t, 1, q.Len())
+
+	x, ack, _, err = q.Recv(ctx)
+	must.NoError(t, err)
+	must.Eq(t, 2, x)
+
+	q.Close()
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, _, _, err = q.Recv(ctxTimeout)
+	must.ErrorIs(t, err, context.DeadlineExceeded)
+
+	ack()
+	_, _, _, err = q.Recv(ctx)
+	must.ErrorIs(t, err, concurrent.ErrClosed)
+	must.Eq(t, 0, q.Len())
+}
+
+func TestAckQueueRedeliver(t *testing.T) {
+	ctx := context.Background()
+	q := concurrent.NewAckQueue[int](20 * time.Millisecond)
+	q.Send(1)
+	x, ack, _, err := q.Recv(ctx)
+	must.NoError(t, err)
+	must.Eq(t, 1, x)
+
+	// the consumer crashed without acknowledging, so the item is delivered again after the visibility timeout
+	start := time.Now()
+	x, ackAgain, _, err := q.Recv(ctx)
+	must.NoError(t, err)
+	must.Eq(t, 1, x)
+	must.GreaterEq(t, 20*time.Millisecond, time.Since(start))
+
+	// acknowledging the expired delivery has no effect
+	ack()
+	must.Eq(t, 1, q.Len())
+	ackAgain()
+	must.Eq(t, 0, q.Len())
+}
@@ -0,0 +1,110 @@
+package concurrent
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+// Broadcast sends every item to all of its subscribers,
+// whereas receivers of an [UnboundedChan] or a channel split the items between them.
+//
+// Each subscriber has its own queue.
+// By default the queue is unbounded so Send never blocks.
+// [NewBroadcastBounded] gives each queue a capacity and an [OverflowPolicy] for slow subscribers.
+//
+// Must be constructed with [NewBroadcast] or [NewBroadcastBounded]
+type Broadcast[T any] struct {
+	// sendMu keeps every subscriber receiving items in the same order
+	sendMu sync.Mutex
+	mu     sync.Mutex
+	subs   map[*Subscription[T]]struct{}
+	// capacity is 0 when unbounded
+	capacity int
+	policy   OverflowPolicy
+}
+
+// Subscription receives the items sent to a [Broadcast] after Subscribe was called.
+type Subscription[T any] struct {
+	b     *Broadcast[T]
+	queue UnboundedChan[T]
+}
+
+// NewBroadcast creates a Broadcast whose subscribers have unbounded queues.
+func NewBroadcast[T any]() *Broadcast[T] {
+	return &Broadcast[T]{subs: make(map[*Subscription[T]]struct{})}
+}
+
+// NewBroadcastBounded creates a Broadcast whose subscribers have queues that hold at most capacity items.
+// The policy decides what happens when a subscriber's queue is full:
+// [Block] makes Send wait for the subscriber, the other policies drop items for that subscriber only.
+func NewBroadcastBounded[T any](capacity int, policy OverflowPolicy) *Broadcast[T] {
+	if capacity < 1 {
+		panic("concurrent: capacity must be positive")
+	}
+	return &Broadcast[T]{subs: make(map[*Subscription[T]]struct{}), capacity: capacity, policy: policy}
+}
+
+// Subscribe returns a Subscription that receives every item sent from now on.
+func (b *Broadcast[T]) Subscribe() *Subscription[T] {
+	s := &Subscription[T]{b: b}
+	if b.capacity == 0 {
+		s.queue = NewUnboundedChan[T]()
+	} else {
+		s.queue = NewBoundedChan[T](b.capacity, b.policy)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Send sends x to all the subscribers.
+func (b *Broadcast[T]) Send(x T) {
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+	b.mu.Lock()
+	subs := make([]*Subscription[T], 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.Unlock()
+	for _, s := range subs {
+		// ErrClosed means the subscription was cancelled during Send
+		_ = s.queue.SendWait(context.Background(), x)
+	}
+}
+
+// Len returns the number of subscribers.
+func (b *Broadcast[T]) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Recv removes the oldest item from the subscription without blocking.
+// It returns false if there is none.
+func (s *Subscription[T]) Recv() (T, bool) {
+	return s.queue.Recv()
+}
+
+// RecvWait removes the oldest item from the subscription, waiting for one to be sent if there is none.
+// It returns [ErrClosed] once the subscription has been cancelled by Unsubscribe,
+// or the cause of ctx if ctx is done before an item is sent.
+func (s *Subscription[T]) RecvWait(ctx context.Context) (T, error) {
+	return s.queue.RecvWait(ctx)
+}
+
+// Seq returns an iterator that receives items with RecvWait until Unsubscribe is called.
+func (s *Subscription[T]) Seq() iter.Seq[T] {
+	return s.queue.Seq()
+}
+
+// Unsubscribe stops the subscription from receiving items and returns the items that have not been received.
+// A Send that is blocked on this subscription returns.
+func (s *Subscription[T]) Unsubscribe() []T {
+	s.b.mu.Lock()
+	delete(s.b.subs, s)
+	s.b.mu.Unlock()
+	return s.queue.Drain()
+}
@@ -0,0 +1,70 @@
+package concurrent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestBroadcast(t *testing.T) {
+	b := concurrent.NewBroadcast[int]()
+	b.Send(0)
+	s1 := b.Subscribe()
+	s2 := b.Subscribe()
+	must.Eq(t, 2, b.Len())
+	b.Send(1)
+	b.Send(2)
+	for _, s := range []*concurrent.Subscription[int]{s1, s2} {
+		x, err := s.RecvWait(context.Background())
+		must.NoError(t, err)
+		must.Eq(t, 1, x)
+	}
+	must.Eq(t, []int{2}, s1.Unsubscribe())
+	must.Eq(t, 1, b.Len())
+	b.Send(3)
+	_, err := s1.RecvWait(context.Background())
+	must.ErrorIs(t, err, concurrent.ErrClosed)
+
+	var got []int
+	for x := range s2.Seq() {
+		got = append(got, x)
+		if x == 3 {
+			break
+		}
+	}
+	must.Eq(t, []int{2, 3}, got)
+}
+
+func TestBroadcastBounded(t *testing.T) {
+	dropping := concurrent.NewBroadcastBounded[int](1, concurrent.DropOldest)
+	slow := dropping.Subscribe()
+	dropping.Send(1)
+	dropping.Send(2)
+	must.Eq(t, []int{2}, slow.Unsubscribe())
+
+	blocking := concurrent.NewBroadcastBounded[int](1, concurrent.Block)
+	fast := blocking.Subscribe()
+	slow = blocking.Subscribe()
+	blocking.Send(1)
+	sent := make(chan struct{})
+	go func() {
+		blocking.Send(2)
+		close(sent)
+	}()
+	x, err := fast.RecvWait(context.Background())
+	must.NoError(t, err)
+	must.Eq(t, 1, x)
+	select {
+	case <-sent:
+		t.Fatal("Send should wait for the slow subscriber")
+	case <-time.After(5 * time.Millisecond):
+	}
+	slow.Unsubscribe()
+	<-sent
+	x, err = fast.RecvWait(context.Background())
+	must.NoError(t, err)
+	must.Eq(t, 2, x)
+}
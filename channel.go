@@ -0,0 +1,1048 @@
+package concurrent
+
+import (
+	"context"
+	"iter"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ChannelMergeSorted merges channels that each receive items in sorted order
+// into a channel that receives all the items in sorted order.
+// Items that are equal are received in the order of the channels given.
+// The returned channel is closed once all the channels are closed.
+//
+// Unlike [ChannelMerge] it must wait for an item from every open channel before sending the smallest,
+// so a channel that is slow to send holds up the others.
+func ChannelMergeSorted[T any](less func(a, b T) bool, cs ...<-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		heads := make([]T, len(cs))
+		open := make([]bool, len(cs))
+		for i, c := range cs {
+			heads[i], open[i] = <-c
+		}
+		for {
+			smallest := -1
+			for i := range cs {
+				if open[i] && (smallest < 0 || less(heads[i], heads[smallest])) {
+					smallest = i
+				}
+			}
+			if smallest < 0 {
+				return
+			}
+			out <- heads[smallest]
+			heads[smallest], open[smallest] = <-cs[smallest]
+		}
+	}()
+	return out
+}
+
+// ChannelTee sends every item received from in to each of the n returned channels.
+// The returned channels are closed once in is closed.
+//
+// Slow consumers block: an output can fall at most two items behind the others,
+// one held by its forwarding go routine and one waiting to be handed to it,
+// after which no more items are received from in until it catches up.
+// Receive from each output in its own go routine,
+// or use [ChannelTeeBuffered] to allow outputs to fall further behind.
+func ChannelTee[T any](in <-chan T, n int) []<-chan T {
+	return ChannelTeeBuffered(in, n, 0)
+}
+
+// ChannelTeeBuffered is the same as [ChannelTee] but each output has a buffer of the given size,
+// so an output can fall size+2 items behind the others before blocking.
+func ChannelTeeBuffered[T any](in <-chan T, n int, size int) []<-chan T {
+	feeds := make([]chan T, n)
+	outs := make([]<-chan T, n)
+	for i := range feeds {
+		feed := make(chan T)
+		out := make(chan T, size)
+		feeds[i] = feed
+		outs[i] = out
+		// forward to out so that a slow output does not stop the others from receiving the current item
+		go func() {
+			defer close(out)
+			for x := range feed {
+				out <- x
+			}
+		}()
+	}
+	go func() {
+		defer func() {
+			for _, feed := range feeds {
+				close(feed)
+			}
+		}()
+		for x := range in {
+			for _, feed := range feeds {
+				feed <- x
+			}
+		}
+	}()
+	return outs
+}
+
+// ChannelMap starts the given number of worker go routines that call fn for each item received from in
+// and send the results to the returned channel.
+// Errors returned by fn are sent to the returned error channel and a panic in fn is sent as a [*PanicError].
+// With more than one worker, the results are not in the order of the items.
+//
+// Both returned channels are closed once in is closed and all items are processed, or once ctx is done.
+// Both must be received from until they are closed so that the workers do not block.
+// The error of ctx is not sent since the caller already has ctx.
+// workers must be positive.
+func ChannelMap[T any, R any](ctx context.Context, in <-chan T, workers int, fn func(T) (R, error)) (<-chan R, <-chan error) {
+	return channelStage(ctx, in, workers, func(item T) (R, bool, error) {
+		result, err := fn(item)
+		return result, true, err
+	})
+}
+
+// ChannelFilter is the same as [ChannelMap] but sends the items for which pred returns true.
+func ChannelFilter[T any](ctx context.Context, in <-chan T, workers int, pred func(T) (bool, error)) (<-chan T, <-chan error) {
+	return channelStage(ctx, in, workers, func(item T) (T, bool, error) {
+		keep, err := pred(item)
+		return item, keep, err
+	})
+}
+
+// channelStage sends the result of fn when fn returns true.
+func channelStage[T any, R any](ctx context.Context, in <-chan T, workers int, fn func(T) (R, bool, error)) (<-chan R, <-chan error) {
+	// checked here since GoConsume would panic in the stage's go routine where it cannot be recovered by the caller
+	if workers < 1 {
+		panic("concurrent: workers must be positive")
+	}
+	out := make(chan R)
+	errs := make(chan error)
+	go func() {
+		defer close(errs)
+		defer close(out)
+		// fn never returns an error to GoConsume, so the only error is the cancellation of ctx
+		_ = GoConsume(ctx, in, workers, func(item T) error {
+			var result R
+			var send bool
+			err := Recovered(func() (err error) {
+				result, send, err = fn(item)
+				return err
+			})
+			if err != nil {
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+				}
+				return nil
+			}
+			if send {
+				select {
+				case out <- result:
+				case <-ctx.Done():
+				}
+			}
+			return nil
+		})
+	}()
+	return out, errs
+}
+
+// ChannelBatch groups the items received from in into batches for bulk processing.
+// A batch is sent once it has maxSize items or maxWait has passed since its first item was received.
+// When in is closed, the last partial batch is sent and the returned channel is closed.
+// When ctx is done, the returned channel is closed and a partial batch is discarded.
+// maxSize and maxWait must be positive.
+func ChannelBatch[T any](ctx context.Context, in <-chan T, maxSize int, maxWait time.Duration) <-chan []T {
+	if maxSize < 1 || maxWait <= 0 {
+		panic("concurrent: maxSize and maxWait must be positive")
+	}
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		timer := time.NewTimer(maxWait)
+		timer.Stop()
+		var batch []T
+		send := func() bool {
+			timer.Stop()
+			select {
+			case out <- batch:
+				batch = nil
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				if !send() {
+					return
+				}
+			case item, ok := <-in:
+				if !ok {
+					if len(batch) > 0 {
+						send()
+					}
+					return
+				}
+				if len(batch) == 0 {
+					timer.Reset(maxWait)
+				}
+				batch = append(batch, item)
+				if len(batch) >= maxSize && !send() {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelDebounce sends an item received from in only once no other item has been received for the duration d,
+// which is useful to react once to a burst of events.
+// When in is closed, the pending item is sent right away and the returned channel is closed.
+// When ctx is done, the returned channel is closed and the pending item is discarded.
+func ChannelDebounce[T any](ctx context.Context, in <-chan T, d time.Duration) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		timer := time.NewTimer(d)
+		timer.Stop()
+		var pending T
+		hasPending := false
+		send := func() bool {
+			select {
+			case out <- pending:
+				var zero T
+				pending, hasPending = zero, false
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				if !send() {
+					return
+				}
+			case item, ok := <-in:
+				if !ok {
+					if hasPending {
+						send()
+					}
+					return
+				}
+				pending, hasPending = item, true
+				timer.Reset(d)
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelThrottle sends the items received from in at most perSecond times per second.
+// The policy decides what happens to items that are received too soon:
+//   - [Block] holds each item until it can be sent, which slows down receiving from in.
+//   - [DropNewest] discards the items received too soon.
+//   - [DropOldest] keeps only the latest item received too soon and sends it once allowed.
+//
+// When in is closed, an item held by [DropOldest] is sent once allowed and then the returned channel is closed.
+// When ctx is done, the returned channel is closed.
+func ChannelThrottle[T any](ctx context.Context, in <-chan T, perSecond float64, policy OverflowPolicy) <-chan T {
+	if perSecond <= 0 {
+		panic("concurrent: rate must be positive")
+	}
+	interval := time.Duration(float64(time.Second) / perSecond)
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		var next time.Time
+		send := func(x T) bool {
+			if delay := time.Until(next); delay > 0 {
+				timer := time.NewTimer(delay)
+				defer timer.Stop()
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			select {
+			case out <- x:
+				next = time.Now().Add(interval)
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		// only used by DropOldest
+		timer := time.NewTimer(interval)
+		timer.Stop()
+		var pending T
+		hasPending := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				if !send(pending) {
+					return
+				}
+				var zero T
+				pending, hasPending = zero, false
+			case item, ok := <-in:
+				if !ok {
+					if hasPending {
+						send(pending)
+					}
+					return
+				}
+				switch policy {
+				case DropNewest:
+					if time.Now().Before(next) {
+						continue
+					}
+					if !send(item) {
+						return
+					}
+				case DropOldest:
+					pending = item
+					if !hasPending {
+						hasPending = true
+						timer.Reset(time.Until(next))
+					}
+				default:
+					if !send(item) {
+						return
+					}
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelFromSeq sends the items of seq to the returned channel,
+// which is closed once seq is exhausted or ctx is done.
+// When ctx is done, seq is stopped.
+func ChannelFromSeq[T any](ctx context.Context, seq iter.Seq[T]) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for x := range seq {
+			select {
+			case out <- x:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelToSeq returns an iterator over the items received from c.
+// The iterator stops when c is closed or ctx is done.
+func ChannelToSeq[T any](ctx context.Context, c <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for {
+			select {
+			case x, ok := <-c:
+				if !ok || !yield(x) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// ChannelWindow sends sliding windows of the last size items received from in,
+// for example to compute a moving average.
+// The first window is sent once size items have been received and then a window is sent after every step items.
+// Each window is a new slice.
+// The returned channel is closed once in is closed or ctx is done. Items that do not fill a window are not sent.
+func ChannelWindow[T any](ctx context.Context, in <-chan T, size int, step int) <-chan []T {
+	if size < 1 || step < 1 {
+		panic("concurrent: size and step must be positive")
+	}
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		window := make([]T, 0, size)
+		// the number of items to receive before the next window
+		remaining := size
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-in:
+				if !ok {
+					return
+				}
+				if len(window) == size {
+					window = append(window[:0], window[1:]...)
+				}
+				window = append(window, item)
+				remaining--
+				if remaining > 0 {
+					continue
+				}
+				remaining = step
+				select {
+				case out <- append([]T(nil), window...):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelRateLimit forwards the items received from in no faster than perSecond,
+// allowing bursts of up to burst items, for feeding a rate limited API.
+// It uses the same limiting as [GoRateLimited].
+// Items wait to be forwarded, which slows down receiving from in.
+// The returned channel is closed once in is closed or ctx is done.
+func ChannelRateLimit[T any](ctx context.Context, in <-chan T, perSecond float64, burst int) <-chan T {
+	rl := newRateLimiter(perSecond, burst)
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-in:
+				if !ok {
+					return
+				}
+				if delay := rl.reserve(); delay > 0 {
+					timer := time.NewTimer(delay)
+					select {
+					case <-timer.C:
+					case <-ctx.Done():
+						timer.Stop()
+						return
+					}
+				}
+				select {
+				case out <- item:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelIdleTimeout forwards the items received from in
+// and closes the returned channel if no item is received within the timeout d,
+// which allows detecting a stalled producer.
+// The returned channel is also closed once in is closed or ctx is done.
+func ChannelIdleTimeout[T any](ctx context.Context, in <-chan T, d time.Duration) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				return
+			case item, ok := <-in:
+				if !ok {
+					return
+				}
+				timer.Stop()
+				select {
+				case out <- item:
+				case <-ctx.Done():
+					return
+				}
+				timer.Reset(d)
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelOrDone forwards the items received from in until in is closed or ctx is done,
+// so that a consumer can range over the returned channel instead of selecting on both.
+// The returned channel is closed in either case.
+func ChannelOrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- item:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelBridge flattens a channel of channels by forwarding all the items of each channel received from in,
+// one channel after the other, for example when producers hand off a stream per request.
+// Unlike [ChannelMerge] the items of a channel are all forwarded before those of the next channel.
+// The returned channel is closed once in and the last channel are closed, or ctx is done.
+func ChannelBridge[T any](ctx context.Context, in <-chan <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for c := range ChannelOrDone(ctx, in) {
+			for item := range ChannelOrDone(ctx, c) {
+				select {
+				case out <- item:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelFromSlice sends the items to the returned channel,
+// which is closed once all the items are sent or ctx is done.
+func ChannelFromSlice[T any](ctx context.Context, items []T) <-chan T {
+	return ChannelFromSeq(ctx, slices.Values(items))
+}
+
+// ChannelToSlice receives all the items from c until c is closed or ctx is done.
+func ChannelToSlice[T any](ctx context.Context, c <-chan T) []T {
+	return slices.Collect(ChannelToSeq(ctx, c))
+}
+
+// ChannelCollect receives exactly n items from c.
+// If c is closed before n items are received it returns the items received and [ErrClosed].
+// If ctx is done first it returns the items received and the cause of ctx.
+// It returns no items for an n of 0 and panics on a negative n.
+func ChannelCollect[T any](ctx context.Context, c <-chan T, n int) ([]T, error) {
+	if n < 0 {
+		panic("concurrent: n must not be negative")
+	}
+	items := make([]T, 0, n)
+	for len(items) < n {
+		item, err := RecvContext(ctx, c)
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// ChannelGenerate runs the producer fn in a go routine, sending the items it emits to the returned channel.
+// emit returns false once ctx is done, at which point fn should return.
+// The returned channel is closed once fn returns.
+//
+// The error returned by fn, or a panic in fn as a [*PanicError], is sent to the returned error channel,
+// which is then closed. The error channel is buffered, so it does not need to be received from.
+func ChannelGenerate[T any](ctx context.Context, fn func(emit func(T) bool) error) (<-chan T, <-chan error) {
+	out := make(chan T)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		err := Recovered(func() error {
+			defer close(out)
+			return fn(func(item T) bool {
+				select {
+				case out <- item:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			})
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return out, errs
+}
+
+// ChannelInterval sends the current time every d until ctx is done, after which the returned channel is closed.
+// A random duration of up to jitter is added to each wait so that many workers do not tick together.
+// If immediate is true, the first tick is sent right away rather than after the first wait.
+//
+// Unlike a time.Ticker there is nothing to stop, and the wait for the next tick starts once the previous tick is received,
+// so a slow receiver gets fewer ticks rather than a backlog.
+func ChannelInterval(ctx context.Context, d time.Duration, jitter time.Duration, immediate bool) <-chan time.Time {
+	out := make(chan time.Time)
+	go func() {
+		defer close(out)
+		send := func() bool {
+			select {
+			case out <- time.Now():
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if immediate && !send() {
+			return
+		}
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		for {
+			wait := d
+			if jitter > 0 {
+				wait += rand.N(jitter)
+			}
+			timer.Reset(wait)
+			select {
+			case <-timer.C:
+				if !send() {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelChunk groups the items received from in into chunks of size items.
+// Unlike [ChannelBatch] there is no time limit, so the chunks only depend on the items.
+// When in is closed, the last chunk is sent even if it is short and the returned channel is closed.
+// When ctx is done, the returned channel is closed and a partial chunk is discarded.
+func ChannelChunk[T any](ctx context.Context, in <-chan T, size int) <-chan []T {
+	if size < 1 {
+		panic("concurrent: size must be positive")
+	}
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		var chunk []T
+		send := func() bool {
+			select {
+			case out <- chunk:
+				chunk = nil
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-in:
+				if !ok {
+					if len(chunk) > 0 {
+						send()
+					}
+					return
+				}
+				chunk = append(chunk, item)
+				if len(chunk) == size && !send() {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelFlatMap calls fn for each item received from in and sends each of the items that fn returns,
+// so that one item can expand into multiple items.
+// The returned channel is closed once in is closed or ctx is done.
+func ChannelFlatMap[T any, R any](ctx context.Context, in <-chan T, fn func(T) []R) <-chan R {
+	out := make(chan R)
+	go func() {
+		defer close(out)
+		for item := range ChannelOrDone(ctx, in) {
+			for _, result := range fn(item) {
+				select {
+				case out <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelFlatMapChan is the same as [ChannelFlatMap] but fn returns a channel,
+// which is drained before the next item's channel, as in [ChannelBridge].
+// fn may be called for the next item before the previous channel is drained.
+func ChannelFlatMapChan[T any, R any](ctx context.Context, in <-chan T, fn func(T) <-chan R) <-chan R {
+	streams := make(chan (<-chan R))
+	go func() {
+		defer close(streams)
+		for item := range ChannelOrDone(ctx, in) {
+			select {
+			case streams <- fn(item):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ChannelBridge(ctx, streams)
+}
+
+// ChannelDistinct forwards the items received from in, skipping an item if an item with the same key was already forwarded.
+// If window is positive, only the keys of the last window forwarded items are remembered to bound the memory used,
+// so a duplicate of an older item is forwarded. Otherwise all the keys are remembered.
+// The returned channel is closed once in is closed or ctx is done.
+func ChannelDistinct[T any, K comparable](ctx context.Context, in <-chan T, key func(T) K, window int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		seen := make(map[K]struct{})
+		// the keys in the order they were seen, only used with a window
+		var keys []K
+		oldest := 0
+		for item := range ChannelOrDone(ctx, in) {
+			k := key(item)
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			if window > 0 {
+				if len(keys) < window {
+					keys = append(keys, k)
+				} else {
+					delete(seen, keys[oldest])
+					keys[oldest] = k
+					oldest = (oldest + 1) % window
+				}
+			}
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelBuffer forwards the items received from in through a queue so that a slow consumer does not block the producer.
+// The queue grows as needed up to max items and shrinks as it is drained.
+// The policy decides what happens when the queue holds max items:
+// [Block] stops receiving from in until an item is forwarded, [DropOldest] and [DropNewest] discard an item.
+// Once in is closed, the items left in the queue are forwarded before the returned channel is closed.
+// The returned channel is closed without forwarding the queue if ctx is done.
+// max must be positive.
+func ChannelBuffer[T any](ctx context.Context, in <-chan T, max int, policy OverflowPolicy) <-chan T {
+	if max < 1 {
+		panic("concurrent: capacity must be positive")
+	}
+	return channelBuffer(ctx, in, max, policy)
+}
+
+// channelBuffer implements ChannelBuffer, with no limit on the queue when max is 0.
+func channelBuffer[T any](ctx context.Context, in <-chan T, max int, policy OverflowPolicy) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		var queue ringBuffer[T]
+		for in != nil || queue.len() > 0 {
+			// a nil channel disables its case of the select
+			recv := in
+			if policy == Block && max > 0 && queue.len() >= max {
+				recv = nil
+			}
+			var send chan<- T
+			var next T
+			if queue.len() > 0 {
+				send = out
+				next = queue.buf[queue.head]
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case send <- next:
+				queue.pop()
+			case item, ok := <-recv:
+				if !ok {
+					in = nil
+					continue
+				}
+				if max > 0 && queue.len() >= max {
+					if policy == DropNewest {
+						continue
+					}
+					queue.pop()
+				}
+				queue.push(item)
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelDemux routes the items received from in to a channel per key so that the items of each key can be processed in order
+// while different keys are processed independently.
+// The first returned function gives the channel of a key, which is created on demand when it is first requested or an item with the key is received.
+// Items are queued per key without limit so that a slow consumer of one key does not block the other keys.
+// Once in is closed, the channels are closed after the items queued for them are received,
+// and a channel requested for a new key is already closed.
+// The channels are closed without receiving the queued items if ctx is done.
+//
+// Each key keeps a go routine and its queue until in is closed, so when keys are short lived
+// call the second returned function once a key is finished to release it.
+// Its channel is closed after the items queued for it are received,
+// and a later item with the key is routed to a new channel.
+func ChannelDemux[T any, K comparable](ctx context.Context, in <-chan T, key func(T) K) (func(K) <-chan T, func(K)) {
+	type route struct {
+		in  chan T
+		out <-chan T
+	}
+	var mu sync.Mutex
+	routes := make(map[K]route)
+	// done is set once in is closed or ctx is done and the inputs of the routes have been closed
+	done := false
+	// lookup must be called with the lock held.
+	lookup := func(k K) route {
+		if r, ok := routes[k]; ok {
+			return r
+		}
+		r := route{in: make(chan T)}
+		if done {
+			close(r.in)
+			r.out = r.in
+			return r
+		}
+		r.out = channelBuffer(ctx, r.in, 0, Block)
+		routes[k] = r
+		return r
+	}
+	go func() {
+		defer func() {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range routes {
+				close(r.in)
+			}
+			done = true
+		}()
+		for item := range ChannelOrDone(ctx, in) {
+			// the lock is held while sending so that release does not close the route in between.
+			// The queue of the route receives right away, so this does not wait on a consumer.
+			mu.Lock()
+			r := lookup(key(item))
+			select {
+			case r.in <- item:
+			case <-ctx.Done():
+			}
+			mu.Unlock()
+		}
+	}()
+	get := func(k K) <-chan T {
+		mu.Lock()
+		defer mu.Unlock()
+		return lookup(k).out
+	}
+	release := func(k K) {
+		mu.Lock()
+		defer mu.Unlock()
+		r, ok := routes[k]
+		if !ok {
+			return
+		}
+		delete(routes, k)
+		if !done {
+			close(r.in)
+		}
+	}
+	return get, release
+}
+
+// ChannelDrainDiscard receives and discards the items of in until in is closed or ctx is done.
+// When a consumer stops early, draining the channel lets the producer finish instead of leaking a go routine blocked on sending.
+// It blocks: use go ChannelDrainDiscard(ctx, in) to drain in the background.
+func ChannelDrainDiscard[T any](ctx context.Context, in <-chan T) {
+	channelDrainDiscard(ctx, in)
+}
+
+// channelDrainDiscard implements ChannelDrainDiscard and reports whether in was closed.
+func channelDrainDiscard[T any](ctx context.Context, in <-chan T) bool {
+	for {
+		select {
+		case _, ok := <-in:
+			if !ok {
+				return true
+			}
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// ChannelWaitAllClosed receives and discards the items of the channels until they are all closed.
+// This sequences the shutdown of a pipeline by waiting for all its stages to finish.
+// It returns the cause of ctx if ctx is done before all the channels are closed.
+func ChannelWaitAllClosed[T any](ctx context.Context, cs ...<-chan T) error {
+	var wg sync.WaitGroup
+	var cut atomic.Bool
+	wg.Add(len(cs))
+	for _, c := range cs {
+		go func() {
+			defer wg.Done()
+			if !channelDrainDiscard(ctx, c) {
+				cut.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+	// ctx may be done after all the channels closed, which is still a success
+	if cut.Load() {
+		return context.Cause(ctx)
+	}
+	return nil
+}
+
+// ChannelWithHeartbeat runs work in a go routine, sending the items it emits to the returned results channel,
+// and sends pulses to the returned heartbeat channel so that a supervisor can detect a wedged worker and restart it.
+// A pulse is sent when work starts, each time work emits, and every interval while emit waits for the item to be received,
+// so a worker that neither emits nor waits on its consumer for longer than the supervisor's timeout is considered wedged.
+// Pulses are dropped when the heartbeat channel already holds one, so the heartbeat does not need to be received from.
+// emit stops waiting once ctx is done, at which point work should return.
+// The results and heartbeat channels are closed once work returns.
+//
+// The error returned by work, or a panic in work as a [*PanicError], is sent to the returned error channel,
+// which is then closed. The error channel is buffered, so it does not need to be received from.
+// interval must be positive.
+func ChannelWithHeartbeat[T any](ctx context.Context, interval time.Duration, work func(ctx context.Context, emit func(T)) error) (results <-chan T, heartbeat <-chan struct{}, errs <-chan error) {
+	if interval <= 0 {
+		panic("concurrent: interval must be positive")
+	}
+	out := make(chan T)
+	pulses := make(chan struct{}, 1)
+	errc := make(chan error, 1)
+	pulse := func() {
+		select {
+		case pulses <- struct{}{}:
+		default:
+		}
+	}
+	go func() {
+		defer close(errc)
+		err := Recovered(func() error {
+			defer close(pulses)
+			defer close(out)
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			pulse()
+			return work(ctx, func(item T) {
+				pulse()
+				for {
+					select {
+					case out <- item:
+						return
+					case <-ticker.C:
+						pulse()
+					case <-ctx.Done():
+						return
+					}
+				}
+			})
+		})
+		if err != nil {
+			errc <- err
+		}
+	}()
+	return out, pulses, errc
+}
+
+// ChannelRebuffer forwards the items received from in to a channel with a buffer of size items,
+// to change the buffering of a channel that is created elsewhere.
+// The returned channel is closed once in is closed or ctx is done.
+func ChannelRebuffer[T any](ctx context.Context, in <-chan T, size int) <-chan T {
+	out := make(chan T, size)
+	go func() {
+		defer close(out)
+		for item := range ChannelOrDone(ctx, in) {
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ChannelMergePriority merges two channels into one, preferring the items of high over the items of low,
+// for example to receive control messages ahead of data messages.
+// An item of low is only sent when high has no item ready,
+// and an item of high that arrives while an item of low waits to be received is sent first.
+// The returned channel is closed once both channels are closed.
+func ChannelMergePriority[T any](high, low <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		// a nil channel disables its case of the select
+		var pending T
+		hasPending := false
+		for high != nil || low != nil || hasPending {
+			if hasPending {
+				select {
+				case out <- pending:
+					hasPending = false
+				case item, ok := <-high:
+					if !ok {
+						high = nil
+						continue
+					}
+					out <- item
+				}
+				continue
+			}
+			select {
+			case item, ok := <-high:
+				if !ok {
+					high = nil
+					continue
+				}
+				out <- item
+				continue
+			default:
+			}
+			select {
+			case item, ok := <-high:
+				if !ok {
+					high = nil
+					continue
+				}
+				out <- item
+			case item, ok := <-low:
+				if !ok {
+					low = nil
+					continue
+				}
+				pending, hasPending = item, true
+			}
+		}
+	}()
+	return out
+}
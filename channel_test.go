@@ -0,0 +1,612 @@
+package concurrent_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func sendAll[T any](items ...T) <-chan T {
+	c := make(chan T)
+	go func() {
+		defer close(c)
+		for _, x := range items {
+			c <- x
+		}
+	}()
+	return c
+}
+
+func recvAll[T any](c <-chan T) []T {
+	var items []T
+	for x := range c {
+		items = append(items, x)
+	}
+	return items
+}
+
+func TestChannelMergeSorted(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+	merged := concurrent.ChannelMergeSorted(less,
+		sendAll(1, 4, 7, 10),
+		sendAll[int](),
+		sendAll(2, 2, 8),
+		sendAll(0, 3, 5, 6, 9),
+	)
+	must.Eq(t, []int{0, 1, 2, 2, 3, 4, 5, 6, 7, 8, 9, 10}, recvAll(merged))
+	must.Nil(t, recvAll(concurrent.ChannelMergeSorted(less)))
+
+	type item struct{ key, shard int }
+	byKey := func(a, b item) bool { return a.key < b.key }
+	stable := concurrent.ChannelMergeSorted(byKey,
+		sendAll(item{1, 0}, item{2, 0}),
+		sendAll(item{1, 1}, item{2, 1}),
+	)
+	must.Eq(t, []item{{1, 0}, {1, 1}, {2, 0}, {2, 1}}, recvAll(stable))
+}
+
+func TestChannelTee(t *testing.T) {
+	outs := concurrent.ChannelTee(sendAll(1, 2, 3), 3)
+	must.SliceLen(t, 3, outs)
+	results := make([][]int, len(outs))
+	errs := concurrent.GoN(len(outs), func(i int) error {
+		results[i] = recvAll(outs[i])
+		return nil
+	})
+	must.Nil(t, errs)
+	for _, result := range results {
+		must.Eq(t, []int{1, 2, 3}, result)
+	}
+
+	// a buffer allows draining one output before the other
+	outs = concurrent.ChannelTeeBuffered(sendAll(1, 2, 3), 2, 2)
+	must.Eq(t, []int{1, 2, 3}, recvAll(outs[1]))
+	must.Eq(t, []int{1, 2, 3}, recvAll(outs[0]))
+}
+
+// recvStage receives from both channels of a pipeline stage until they are closed.
+func recvStage[T any](out <-chan T, errs <-chan error) ([]T, []error) {
+	var results []T
+	var errors []error
+	for out != nil || errs != nil {
+		select {
+		case x, ok := <-out:
+			if !ok {
+				out = nil
+				continue
+			}
+			results = append(results, x)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			errors = append(errors, err)
+		}
+	}
+	return results, errors
+}
+
+func TestChannelMapFilter(t *testing.T) {
+	ctx := context.Background()
+	errOdd := errors.New("odd")
+	numbers := sendAll(1, 2, 3, 4, 5, 6)
+	even, errs := concurrent.ChannelFilter(ctx, numbers, 2, func(x int) (bool, error) {
+		if x == 5 {
+			return false, errOdd
+		}
+		return x%2 == 0, nil
+	})
+	filterErrs := make(chan []error)
+	go func() {
+		filterErrs <- recvAll(errs)
+	}()
+	strs, strErrs := concurrent.ChannelMap(ctx, even, 3, func(x int) (string, error) {
+		if x == 4 {
+			panic("four")
+		}
+		return strconv.Itoa(x), nil
+	})
+	results, mapErrs := recvStage(strs, strErrs)
+	slices.Sort(results)
+	must.Eq(t, []string{"2", "6"}, results)
+	must.SliceLen(t, 1, mapErrs)
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(mapErrs[0], &pe))
+	must.Eq(t, []error{errOdd}, <-filterErrs)
+
+	for _, workers := range []int{0, -1} {
+		err := concurrent.Recovered(func() error {
+			concurrent.ChannelMap(ctx, make(chan int), workers, func(x int) (int, error) { return x, nil })
+			return nil
+		})
+		must.Error(t, err)
+		err = concurrent.Recovered(func() error {
+			concurrent.ChannelFilter(ctx, make(chan int), workers, func(x int) (bool, error) { return true, nil })
+			return nil
+		})
+		must.Error(t, err)
+	}
+}
+
+func TestChannelMapCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out, errs := concurrent.ChannelMap(ctx, in, 2, func(x int) (int, error) {
+		return x, nil
+	})
+	in <- 1
+	cancel()
+	results, stageErrs := recvStage(out, errs)
+	must.LessEq(t, 1, len(results))
+	must.Nil(t, stageErrs)
+}
+
+func TestChannelBatch(t *testing.T) {
+	ctx := context.Background()
+	batches := concurrent.ChannelBatch(ctx, sendAll(1, 2, 3, 4, 5), 2, time.Hour)
+	must.Eq(t, [][]int{{1, 2}, {3, 4}, {5}}, recvAll(batches))
+
+	in := make(chan int)
+	batches = concurrent.ChannelBatch(ctx, in, 10, time.Millisecond)
+	in <- 1
+	in <- 2
+	must.Eq(t, []int{1, 2}, <-batches)
+	in <- 3
+	must.Eq(t, []int{3}, <-batches)
+	close(in)
+	must.Nil(t, recvAll(batches))
+
+	ctx, cancel := context.WithCancel(ctx)
+	in = make(chan int)
+	batches = concurrent.ChannelBatch(ctx, in, 10, time.Hour)
+	in <- 1
+	cancel()
+	must.Nil(t, recvAll(batches))
+	for _, args := range []struct {
+		maxSize int
+		maxWait time.Duration
+	}{{0, time.Hour}, {-1, time.Hour}, {10, 0}, {10, -time.Second}} {
+		err := concurrent.Recovered(func() error {
+			concurrent.ChannelBatch(ctx, in, args.maxSize, args.maxWait)
+			return nil
+		})
+		must.Error(t, err)
+	}
+}
+
+func TestChannelDebounce(t *testing.T) {
+	ctx := context.Background()
+	in := make(chan int)
+	debounced := concurrent.ChannelDebounce(ctx, in, 20*time.Millisecond)
+	in <- 1
+	in <- 2
+	in <- 3
+	must.Eq(t, 3, <-debounced)
+	in <- 4
+	close(in)
+	must.Eq(t, []int{4}, recvAll(debounced))
+}
+
+func TestChannelThrottle(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+	blocked := concurrent.ChannelThrottle(ctx, sendAll(1, 2, 3), 100, concurrent.Block)
+	must.Eq(t, []int{1, 2, 3}, recvAll(blocked))
+	must.Greater(t, 15*time.Millisecond, time.Since(start))
+
+	dropped := concurrent.ChannelThrottle(ctx, sendAll(1, 2, 3), 1, concurrent.DropNewest)
+	must.Eq(t, []int{1}, recvAll(dropped))
+
+	in := make(chan int)
+	latest := concurrent.ChannelThrottle(ctx, in, 20, concurrent.DropOldest)
+	in <- 1
+	must.Eq(t, 1, <-latest)
+	in <- 2
+	in <- 3
+	close(in)
+	must.Eq(t, []int{3}, recvAll(latest))
+}
+
+func TestChannelSeq(t *testing.T) {
+	ctx := context.Background()
+	c := concurrent.ChannelFromSeq(ctx, slices.Values([]int{1, 2, 3}))
+	must.Eq(t, []int{1, 2, 3}, slices.Collect(concurrent.ChannelToSeq(ctx, c)))
+
+	var got []int
+	for x := range concurrent.ChannelToSeq(ctx, sendAll(1, 2, 3)) {
+		got = append(got, x)
+		if x == 2 {
+			break
+		}
+	}
+	must.Eq(t, []int{1, 2}, got)
+
+	ctx, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	forever := func(yield func(int) bool) {
+		defer close(stopped)
+		for i := 0; yield(i); i++ {
+		}
+	}
+	c = concurrent.ChannelFromSeq(ctx, forever)
+	must.Eq(t, 0, <-c)
+	cancel()
+	<-stopped
+	must.SliceEmpty(t, slices.Collect(concurrent.ChannelToSeq(ctx, make(chan int))))
+}
+
+func TestChannelWindow(t *testing.T) {
+	ctx := context.Background()
+	windows := concurrent.ChannelWindow(ctx, sendAll(1, 2, 3, 4, 5), 3, 1)
+	must.Eq(t, [][]int{{1, 2, 3}, {2, 3, 4}, {3, 4, 5}}, recvAll(windows))
+
+	windows = concurrent.ChannelWindow(ctx, sendAll(1, 2, 3, 4, 5, 6, 7), 2, 3)
+	must.Eq(t, [][]int{{1, 2}, {4, 5}}, recvAll(windows))
+
+	windows = concurrent.ChannelWindow(ctx, sendAll(1, 2), 3, 1)
+	must.Nil(t, recvAll(windows))
+}
+
+func TestChannelRateLimit(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+	limited := concurrent.ChannelRateLimit(ctx, sendAll(1, 2, 3, 4, 5), 100, 3)
+	must.Eq(t, []int{1, 2, 3, 4, 5}, recvAll(limited))
+	// the burst of 3 goes right away and the other 2 wait 10ms each
+	must.Greater(t, 15*time.Millisecond, time.Since(start))
+
+	ctx, cancel := context.WithCancel(ctx)
+	in := make(chan int)
+	limited = concurrent.ChannelRateLimit(ctx, in, 0.001, 1)
+	in <- 1
+	must.Eq(t, 1, <-limited)
+	in <- 2
+	cancel()
+	must.Nil(t, recvAll(limited))
+}
+
+func TestChannelIdleTimeout(t *testing.T) {
+	ctx := context.Background()
+	must.Eq(t, []int{1, 2}, recvAll(concurrent.ChannelIdleTimeout(ctx, sendAll(1, 2), time.Hour)))
+
+	in := make(chan int)
+	out := concurrent.ChannelIdleTimeout(ctx, in, 5*time.Millisecond)
+	in <- 1
+	// the time waiting for the consumer does not count
+	time.Sleep(10 * time.Millisecond)
+	must.Eq(t, 1, <-out)
+	_, ok := <-out
+	must.False(t, ok)
+}
+
+func TestChannelOrDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	must.Eq(t, []int{1, 2}, recvAll(concurrent.ChannelOrDone(ctx, sendAll(1, 2))))
+
+	in := make(chan int)
+	out := concurrent.ChannelOrDone(ctx, in)
+	in <- 1
+	must.Eq(t, 1, <-out)
+	cancel()
+	must.Nil(t, recvAll(out))
+}
+
+func TestChannelBridge(t *testing.T) {
+	ctx := context.Background()
+	streams := sendAll(sendAll(1, 2), sendAll[int](), sendAll(3))
+	must.Eq(t, []int{1, 2, 3}, recvAll(concurrent.ChannelBridge(ctx, streams)))
+}
+
+func TestChannelSlice(t *testing.T) {
+	ctx := context.Background()
+	must.Eq(t, []int{1, 2, 3}, concurrent.ChannelToSlice(ctx, concurrent.ChannelFromSlice(ctx, []int{1, 2, 3})))
+
+	c := concurrent.ChannelFromSlice(ctx, []int{1, 2, 3})
+	items, err := concurrent.ChannelCollect(ctx, c, 2)
+	must.NoError(t, err)
+	must.Eq(t, []int{1, 2}, items)
+	items, err = concurrent.ChannelCollect(ctx, c, 2)
+	must.ErrorIs(t, err, concurrent.ErrClosed)
+	must.Eq(t, []int{3}, items)
+	items, err = concurrent.ChannelCollect(ctx, make(chan int), 0)
+	must.NoError(t, err)
+	must.SliceEmpty(t, items)
+	err = concurrent.Recovered(func() error {
+		_, err := concurrent.ChannelCollect(ctx, c, -1)
+		return err
+	})
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(err, &pe))
+
+	timeout, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	items, err = concurrent.ChannelCollect(timeout, make(chan int), 1)
+	must.ErrorIs(t, err, context.DeadlineExceeded)
+	must.SliceEmpty(t, items)
+	must.Nil(t, concurrent.ChannelToSlice(timeout, make(chan int)))
+}
+
+func TestChannelGenerate(t *testing.T) {
+	ctx := context.Background()
+	out, errs := concurrent.ChannelGenerate(ctx, func(emit func(int) bool) error {
+		for i := 1; i <= 3; i++ {
+			emit(i)
+		}
+		return nil
+	})
+	must.Eq(t, []int{1, 2, 3}, recvAll(out))
+	must.Nil(t, recvAll(errs))
+
+	errStop := errors.New("stop")
+	out, errs = concurrent.ChannelGenerate(ctx, func(emit func(int) bool) error {
+		emit(1)
+		return errStop
+	})
+	must.Eq(t, []int{1}, recvAll(out))
+	must.Eq(t, []error{errStop}, recvAll(errs))
+
+	out, errs = concurrent.ChannelGenerate(ctx, func(emit func(int) bool) error {
+		panic("generate")
+	})
+	must.Nil(t, recvAll(out))
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(<-errs, &pe))
+
+	ctx, cancel := context.WithCancel(ctx)
+	out, errs = concurrent.ChannelGenerate(ctx, func(emit func(int) bool) error {
+		for i := 0; emit(i); i++ {
+		}
+		return context.Cause(ctx)
+	})
+	must.Eq(t, 0, <-out)
+	cancel()
+	must.ErrorIs(t, <-errs, context.Canceled)
+}
+
+func TestChannelInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	start := time.Now()
+	ticks := concurrent.ChannelInterval(ctx, 5*time.Millisecond, 5*time.Millisecond, false)
+	<-ticks
+	<-ticks
+	must.Greater(t, 10*time.Millisecond, time.Since(start))
+	cancel()
+	must.Nil(t, recvAll(ticks))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	start = time.Now()
+	ticks = concurrent.ChannelInterval(ctx, time.Hour, 0, true)
+	<-ticks
+	must.Less(t, time.Minute, time.Since(start))
+}
+
+func TestChannelChunk(t *testing.T) {
+	ctx := context.Background()
+	must.Eq(t, [][]int{{1, 2}, {3, 4}, {5}}, recvAll(concurrent.ChannelChunk(ctx, sendAll(1, 2, 3, 4, 5), 2)))
+	must.Eq(t, [][]int{{1, 2}}, recvAll(concurrent.ChannelChunk(ctx, sendAll(1, 2), 2)))
+	must.Nil(t, recvAll(concurrent.ChannelChunk(ctx, sendAll[int](), 2)))
+}
+
+func TestChannelFlatMap(t *testing.T) {
+	ctx := context.Background()
+	repeat := func(x int) []int { return slices.Repeat([]int{x}, x) }
+	must.Eq(t, []int{1, 2, 2, 3, 3, 3}, recvAll(concurrent.ChannelFlatMap(ctx, sendAll(1, 0, 2, 3), repeat)))
+
+	repeatChan := func(x int) <-chan int { return sendAll(repeat(x)...) }
+	must.Eq(t, []int{1, 2, 2, 3, 3, 3}, recvAll(concurrent.ChannelFlatMapChan(ctx, sendAll(1, 0, 2, 3), repeatChan)))
+}
+
+func TestChannelDistinct(t *testing.T) {
+	ctx := context.Background()
+	identity := func(x int) int { return x }
+	must.Eq(t, []int{1, 2, 3}, recvAll(concurrent.ChannelDistinct(ctx, sendAll(1, 2, 1, 3, 2, 1), identity, 0)))
+	must.Eq(t, []int{1, 2, 3, 1, 2, 3}, recvAll(concurrent.ChannelDistinct(ctx, sendAll(1, 2, 2, 3, 1, 2, 3), identity, 2)))
+
+	type event struct {
+		id   int
+		data string
+	}
+	events := sendAll(event{1, "a"}, event{1, "b"}, event{2, "c"})
+	byID := func(e event) int { return e.id }
+	must.Eq(t, []event{{1, "a"}, {2, "c"}}, recvAll(concurrent.ChannelDistinct(ctx, events, byID, 0)))
+}
+
+func TestChannelBuffer(t *testing.T) {
+	ctx := context.Background()
+	must.Eq(t, []int{1, 2, 3}, recvAll(concurrent.ChannelBuffer(ctx, sendAll(1, 2, 3), 2, concurrent.Block)))
+
+	// the producer is not blocked by the consumer until max items are queued
+	in := make(chan int)
+	out := concurrent.ChannelBuffer(ctx, in, 3, concurrent.Block)
+	for i := range 3 {
+		in <- i
+	}
+	select {
+	case in <- 3:
+		t.Fatal("expected the buffer to be full")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(in)
+	must.Eq(t, []int{0, 1, 2}, recvAll(out))
+
+	for _, test := range []struct {
+		policy   concurrent.OverflowPolicy
+		expected []int
+	}{
+		{concurrent.DropOldest, []int{3, 4}},
+		{concurrent.DropNewest, []int{0, 1}},
+	} {
+		in := make(chan int)
+		out := concurrent.ChannelBuffer(ctx, in, 2, test.policy)
+		for i := range 5 {
+			in <- i
+		}
+		close(in)
+		must.Eq(t, test.expected, recvAll(out))
+	}
+}
+
+func TestChannelDemux(t *testing.T) {
+	ctx := context.Background()
+	type event struct {
+		id  string
+		seq int
+	}
+	in := make(chan event)
+	route, _ := concurrent.ChannelDemux(ctx, in, func(e event) string { return e.id })
+	a := route("a")
+	for _, e := range []event{{"a", 1}, {"b", 1}, {"a", 2}, {"b", 2}, {"c", 1}} {
+		in <- e
+	}
+	close(in)
+	// items of a key are queued even when its channel is not received from yet
+	must.Eq(t, []event{{"b", 1}, {"b", 2}}, recvAll(route("b")))
+	must.Eq(t, []event{{"a", 1}, {"a", 2}}, recvAll(a))
+	must.Eq(t, []event{{"c", 1}}, recvAll(route("c")))
+	// after in is closed a new key gets a closed channel
+	must.SliceEmpty(t, recvAll(route("d")))
+}
+
+func TestChannelDemuxRelease(t *testing.T) {
+	ctx := context.Background()
+	in := make(chan int)
+	defer close(in)
+	route, release := concurrent.ChannelDemux(ctx, in, func(x int) int { return x % 2 })
+	in <- 1
+	in <- 3
+	// each stage handles an item before receiving the next,
+	// so once 4 is sent the router has received 2 and queued 3
+	in <- 2
+	in <- 4
+	odd := route(1)
+	// the route of 0 is created before counting so that only the route of 1 changes the count
+	route(0)
+	goroutines := runtime.NumGoroutine()
+	release(1)
+	// the queued items are received before the channel is closed
+	must.Eq(t, []int{1, 3}, recvAll(odd))
+	// the go routine of the key exits
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() >= goroutines; {
+		must.True(t, time.Now().Before(deadline))
+		time.Sleep(time.Millisecond)
+	}
+	// the key is forgotten, so a later item gets a new channel
+	in <- 5
+	must.Eq(t, 5, <-route(1))
+	release(1)
+	release(1)
+}
+
+func TestChannelDrainDiscard(t *testing.T) {
+	ctx := context.Background()
+	in := make(chan int)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 10 {
+			in <- i
+		}
+		close(in)
+	}()
+	<-in
+	// the consumer stopped early but the producer finishes
+	concurrent.ChannelDrainDiscard(ctx, in)
+	<-done
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	concurrent.ChannelDrainDiscard(ctx, make(chan int))
+}
+
+func TestChannelWaitAllClosed(t *testing.T) {
+	ctx := context.Background()
+	must.NoError(t, concurrent.ChannelWaitAllClosed(ctx, sendAll(1, 2), sendAll(3)))
+
+	open := make(chan int)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	must.ErrorIs(t, concurrent.ChannelWaitAllClosed(ctx, sendAll(1), open), context.DeadlineExceeded)
+
+	// ctx being done does not matter when no channel is cut short
+	<-ctx.Done()
+	must.NoError(t, concurrent.ChannelWaitAllClosed[int](ctx))
+}
+
+func TestChannelWithHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	results, heartbeat, errs := concurrent.ChannelWithHeartbeat(ctx, time.Millisecond, func(ctx context.Context, emit func(int)) error {
+		emit(1)
+		emit(2)
+		return nil
+	})
+	// the consumer is slow but the worker keeps pulsing while it waits
+	time.Sleep(5 * time.Millisecond)
+	_, ok := <-heartbeat
+	must.True(t, ok)
+	must.Eq(t, []int{1, 2}, recvAll(results))
+	must.NoError(t, <-errs)
+	for range heartbeat {
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	errWedged := errors.New("wedged")
+	results, heartbeat, errs = concurrent.ChannelWithHeartbeat(ctx, time.Millisecond, func(ctx context.Context, emit func(int)) error {
+		<-ctx.Done()
+		return errWedged
+	})
+	<-heartbeat
+	// a wedged worker stops pulsing
+	_, pulsed, _ := concurrent.RecvTimeout(heartbeat, 10*time.Millisecond)
+	must.False(t, pulsed)
+	cancel()
+	must.SliceEmpty(t, recvAll(results))
+	must.ErrorIs(t, <-errs, errWedged)
+	err := concurrent.Recovered(func() error {
+		concurrent.ChannelWithHeartbeat(ctx, 0, func(ctx context.Context, emit func(int)) error { return nil })
+		return nil
+	})
+	must.Error(t, err)
+}
+
+func TestChannelRebuffer(t *testing.T) {
+	ctx := context.Background()
+	in := make(chan int)
+	out := concurrent.ChannelRebuffer(ctx, in, 2)
+	must.Eq(t, 2, cap(out))
+	// the producer of an unbuffered channel is not blocked while the buffer has room
+	in <- 1
+	in <- 2
+	in <- 3
+	close(in)
+	must.Eq(t, []int{1, 2, 3}, recvAll(out))
+}
+
+func TestChannelMergePriority(t *testing.T) {
+	high := make(chan string, 3)
+	low := make(chan string, 3)
+	low <- "data1"
+	low <- "data2"
+	high <- "control1"
+	high <- "control2"
+	out := concurrent.ChannelMergePriority(high, low)
+	must.Eq(t, "control1", <-out)
+	must.Eq(t, "control2", <-out)
+	high <- "control3"
+	close(high)
+	close(low)
+	received := recvAll(out)
+	// control3 might have been sent after data1 was received
+	must.SliceContainsAll(t, []string{"data1", "data2", "control3"}, received)
+	must.Eq(t, "data2", received[len(received)-1])
+
+	must.Eq(t, []int{1, 2}, recvAll(concurrent.ChannelMergePriority(sendAll(1, 2), nil)))
+}
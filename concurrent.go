@@ -1,10 +1,15 @@
 package concurrent
 
 import (
+	"cmp"
+	"context"
+	"runtime"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gregwebs/errors"
-	"github.com/gregwebs/go-recovery"
 )
 
 // GoN runs a function in parallel multiple times using n goroutines.
@@ -12,6 +17,9 @@ import (
 // It recovers any panics that occur during the execution of the function
 // and returns them as a slice of errors. If no errors occurred, nil will be returned.
 //
+// A recovered panic is a [*PanicError] holding the original panic value and the stack trace of the panic.
+// Use [errors.As] to retrieve it. Format it with "%+v" to print the stack trace.
+//
 // Use [errors.Join] to combine the individual errors into a single error.
 func GoN(n int, fn func(int) error) []error {
 	return GoConcurrent().GoN(n, fn)
@@ -31,41 +39,561 @@ func GoEach[T any](all []T, fn func(T) error) []error {
 	})
 }
 
+// GoNOrdered is the same as [GoN] but also returns the result of each index.
+// Although fn runs concurrently, results[i] is always the result of fn(i).
+// If fn(i) returns an error or panics, results[i] is the zero value.
+func GoNOrdered[R any](n int, fn func(int) (R, error)) ([]R, []error) {
+	results := make([]R, n)
+	errs := GoN(n, func(i int) error {
+		result, err := fn(i)
+		if err != nil {
+			return err
+		}
+		results[i] = result
+		return nil
+	})
+	return results, errs
+}
+
+// GoEachKV runs a go routine for each key and value of a map.
+// It returns the errors keyed by the key that failed.
+//
+// It recovers any panics that occur during the execution of the function
+// and returns them as errors. If no errors occurred, nil will be returned.
+func GoEachKV[K comparable, V any](m map[K]V, fn func(K, V) error) map[K]error {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	errs := GoConcurrent().goN(len(keys), func(n int) error {
+		k := keys[n]
+		return fn(k, m[k])
+	})
+	var keyErrs map[K]error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if keyErrs == nil {
+			keyErrs = make(map[K]error)
+		}
+		keyErrs[keys[i]] = err
+	}
+	return keyErrs
+}
+
+// GoEachCheckpoint is the same as [GoEach] but supports resuming an interrupted run.
+// After fn succeeds for the item at an index, checkpoint is called with the index, so it can be persisted.
+// Calls to checkpoint are never concurrent.
+// The indices given in completed are skipped.
+func GoEachCheckpoint[T any](all []T, completed []int, checkpoint func(int), fn func(T) error) []error {
+	skip := make(map[int]struct{}, len(completed))
+	for _, i := range completed {
+		skip[i] = struct{}{}
+	}
+	pending := make([]int, 0, len(all))
+	for i := range all {
+		if _, ok := skip[i]; !ok {
+			pending = append(pending, i)
+		}
+	}
+
+	var mu sync.Mutex
+	return GoN(len(pending), func(n int) error {
+		i := pending[n]
+		if err := fn(all[i]); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		checkpoint(i)
+		return nil
+	})
+}
+
+// GoNAuto is the same as [GoN] but limits the number of go routines running at once to runtime.GOMAXPROCS.
+// Use this for CPU-bound work where launching a go routine per item only adds scheduling overhead.
+//
+// GOMAXPROCS only follows a container's cgroup CPU quota with Go 1.25 or later
+// and when the go.mod of the main module declares go 1.25 or later, which this module does not require.
+// Otherwise it is the number of CPUs of the machine, so set GOMAXPROCS when running in a container with a CPU limit.
+func GoNAuto(n int, fn func(int) error) []error {
+	return GoNLimit(n, runtime.GOMAXPROCS(0), fn)
+}
+
+// GoEachAuto is the same as [GoEach] but limits the number of go routines running at once to runtime.GOMAXPROCS.
+// See [GoNAuto].
+func GoEachAuto[T any](all []T, fn func(T) error) []error {
+	return GoNAuto(len(all), func(n int) error {
+		item := all[n]
+		return fn(item)
+	})
+}
+
+// GoReduce runs mapFn in a go routine for each item and then combines the results with reduce.
+// Results are combined from left to right in the order of items, so reduce does not need to be commutative.
+// Items that return an error are left out of the reduction.
+//
+// It recovers any panics that occur during the execution of mapFn
+// and returns them as a slice of errors. If no errors occurred, nil will be returned.
+func GoReduce[T any, R any](items []T, mapFn func(T) (R, error), reduce func(R, R) R) (R, []error) {
+	results := make([]R, len(items))
+	mapped := make([]bool, len(items))
+	errs := GoN(len(items), func(n int) error {
+		result, err := mapFn(items[n])
+		if err != nil {
+			return err
+		}
+		results[n] = result
+		mapped[n] = true
+		return nil
+	})
+
+	var acc R
+	first := true
+	for i, result := range results {
+		if !mapped[i] {
+			continue
+		}
+		if first {
+			acc = result
+			first = false
+		} else {
+			acc = reduce(acc, result)
+		}
+	}
+	return acc, errs
+}
+
+// GoFilter runs pred in a go routine for each item and returns the items for which pred returned true.
+// The kept items are returned in their original order.
+// Items for which pred returns an error are not kept.
+//
+// It recovers any panics that occur during the execution of pred
+// and returns them as a slice of errors. If no errors occurred, nil will be returned.
+func GoFilter[T any](items []T, pred func(T) (bool, error)) ([]T, []error) {
+	keep := make([]bool, len(items))
+	errs := GoN(len(items), func(n int) error {
+		ok, err := pred(items[n])
+		if err != nil {
+			return err
+		}
+		keep[n] = ok
+		return nil
+	})
+
+	kept := make([]T, 0, len(items))
+	for i, item := range items {
+		if keep[i] {
+			kept = append(kept, item)
+		}
+	}
+	return kept, errs
+}
+
+// GoConsume starts the given number of worker go routines that call fn for each item received from ch.
+// It returns once ch is closed and drained or ctx is done.
+//
+// Errors returned by fn are collected and returned. A panic in fn is recovered and returned as an error.
+// If the workers were stopped by ctx before ch was closed, the cause of the cancellation is returned as the last error.
+// workers must be positive.
+func GoConsume[T any](ctx context.Context, ch <-chan T, workers int, fn func(T) error) []error {
+	if workers < 1 {
+		panic("concurrent: workers must be positive")
+	}
+	workerErrs := make([][]error, workers)
+	var canceled atomic.Bool
+	errs := GoN(workers, func(w int) error {
+		for {
+			if ctx.Err() != nil {
+				canceled.Store(true)
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				canceled.Store(true)
+				return nil
+			case item, ok := <-ch:
+				if !ok {
+					return nil
+				}
+				if err := Recovered(func() error { return fn(item) }); err != nil {
+					workerErrs[w] = append(workerErrs[w], err)
+				}
+			}
+		}
+	})
+
+	for _, errsW := range workerErrs {
+		errs = append(errs, errsW...)
+	}
+	if canceled.Load() {
+		errs = append(errs, context.Cause(ctx))
+	}
+	return errors.Joins(errs...)
+}
+
+// GoNLimit is the same as [GoN] but runs fn using only limit go routines.
+//
+// The indices are split evenly between the go routines.
+// A go routine that finishes its indices steals half of the remaining indices of another go routine,
+// so a few slow indices do not leave the other go routines idle.
+func GoNLimit(n int, limit int, fn func(int) error) []error {
+	if limit > n {
+		limit = n
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	errs := make([]error, n)
+	ranges := make([]indexRange, limit)
+	for w := range ranges {
+		ranges[w].lo = w * n / limit
+		ranges[w].hi = (w + 1) * n / limit
+	}
+	_ = GoN(limit, func(w int) error {
+		own := &ranges[w]
+		for {
+			i, ok := own.pop()
+			if !ok {
+				if !own.stealFrom(ranges, w) {
+					return nil
+				}
+				continue
+			}
+			errs[i] = Recovered(func() error { return fn(i) })
+		}
+	})
+	return errors.Joins(errs...)
+}
+
+// GoDynamic runs fn for each item of seed and for each item that fn emits, using limit go routines.
+// This supports work that discovers more work as it goes, such as crawling or walking a tree.
+// Items are processed in the order they were added.
+// GoDynamic returns once all items have been processed and no more items were emitted.
+//
+// emit must only be called while fn is running.
+//
+// It recovers any panics that occur during the execution of fn
+// and returns them as a slice of errors. If no errors occurred, nil will be returned.
+func GoDynamic[T any](limit int, seed []T, fn func(item T, emit func(T)) error) []error {
+	if limit < 1 {
+		limit = 1
+	}
+	var mu sync.Mutex
+	cond := sync.NewCond(&mu)
+	queue := slices.Clone(seed)
+	// the number of items queued or running
+	pending := len(queue)
+	var errs []error
+
+	emit := func(item T) {
+		mu.Lock()
+		queue = append(queue, item)
+		pending++
+		mu.Unlock()
+		cond.Signal()
+	}
+
+	_ = GoN(limit, func(_ int) error {
+		for {
+			mu.Lock()
+			for len(queue) == 0 && pending > 0 {
+				cond.Wait()
+			}
+			if pending == 0 {
+				mu.Unlock()
+				return nil
+			}
+			item := queue[0]
+			var zero T
+			queue[0] = zero
+			queue = queue[1:]
+			mu.Unlock()
+
+			err := Recovered(func() error { return fn(item, emit) })
+
+			mu.Lock()
+			if err != nil {
+				errs = append(errs, err)
+			}
+			pending--
+			finished := pending == 0
+			mu.Unlock()
+			if finished {
+				cond.Broadcast()
+			}
+		}
+	})
+	return errs
+}
+
+// GoGrid runs fn for each cell of a rows by cols grid using at most limit go routines in total.
+// Use this instead of nesting [GoN], which launches rows*cols go routines.
+// Errors are returned in row-major order.
+func GoGrid(rows int, cols int, limit int, fn func(r int, c int) error) []error {
+	return GoNLimit(rows*cols, limit, func(i int) error {
+		return fn(i/cols, i%cols)
+	})
+}
+
+// indexRange is the indices [lo, hi) that a worker of [GoNLimit] has yet to run.
+type indexRange struct {
+	mu sync.Mutex
+	lo int
+	hi int
+}
+
+func (r *indexRange) pop() (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lo >= r.hi {
+		return 0, false
+	}
+	i := r.lo
+	r.lo++
+	return i, true
+}
+
+// stealHalf removes the upper half of the remaining indices.
+func (r *indexRange) stealHalf() (lo int, hi int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	half := (r.hi - r.lo + 1) / 2
+	r.hi -= half
+	return r.hi, r.hi + half
+}
+
+// stealFrom moves half of the remaining indices of another range into r.
+// It returns false when there is nothing left to steal.
+func (r *indexRange) stealFrom(ranges []indexRange, self int) bool {
+	for offset := 1; offset < len(ranges); offset++ {
+		victim := &ranges[(self+offset)%len(ranges)]
+		lo, hi := victim.stealHalf()
+		if lo < hi {
+			r.mu.Lock()
+			r.lo, r.hi = lo, hi
+			r.mu.Unlock()
+			return true
+		}
+	}
+	return false
+}
+
 // [GoConcurrent] is the default implementation for launching a routine.
 // It just uses the `go` keyword.
 func GoConcurrent() GoRoutine {
-	return GoRoutine(func(work func()) { go work() })
+	return GoRoutine(func(work func() error) { go work() })
 }
 
 // [GoSerial] allows for running in serial for debugging
 func GoSerial() GoRoutine {
-	return GoRoutine(func(work func()) { work() })
+	return GoRoutine(func(work func() error) { _ = work() })
 }
 
 // GoRoutine allows for inserting hooks before launching Go routines
 // [GoConcurrent] is the default implementation.
 // [GoSerial] allows for running in serial for debugging
-type GoRoutine func(func())
+//
+// work returns the error of the launched function so that a GoRoutine can observe it, for example to log it.
+// The error, or a panic converted to a [*PanicError], has already been recorded for [GoRoutine.GoN] or [Group.Wait]
+// by the time work returns, so a GoRoutine can ignore it.
+//
+// GoRoutine used to be func(work func()): to migrate a custom launcher,
+// change func(work func()) {…} to func(work func() error) {…} and call work as _ = work().
+type GoRoutine func(work func() error)
 
 // The same as [GoN] but with go routine launching configured by a GoRoutine.
 func (gr GoRoutine) GoN(n int, fn func(int) error) []error {
+	return errors.Joins(gr.goN(n, fn)...)
+}
+
+// goN returns the error for each index, so errs[i] is the result of fn(i).
+func (gr GoRoutine) goN(n int, fn func(int) error) []error {
 	errs := make([]error, n)
 	var wg sync.WaitGroup
 	for i := 0; i < n; i++ {
 		i := i
 		wg.Add(1)
-		gr(func() {
-			recovery.GoHandler(func(err error) { errs[i] = err }, func() error {
-				defer wg.Done()
-				errs[i] = fn(i)
+		gr(func() error {
+			defer wg.Done()
+			errs[i] = Recovered(func() error { return fn(i) })
+			return errs[i]
+		})
+	}
+	wg.Wait()
+	return errs
+}
+
+// GoNRetry is the same as [GoN] but re-runs the indices that failed.
+// fn is called at most attempts times for each index.
+// attempts less than 1 is the same as 1, so fn is always called at least once for each index.
+// The errors returned are those of the last attempt of each index that never succeeded.
+func GoNRetry(n int, attempts int, fn func(int) error) []error {
+	return GoConcurrent().GoNRetry(n, attempts, fn)
+}
+
+// The same as [GoNRetry] but with go routine launching configured by a GoRoutine.
+func (gr GoRoutine) GoNRetry(n int, attempts int, fn func(int) error) []error {
+	attempts = max(attempts, 1)
+	pending := make([]int, n)
+	for i := range pending {
+		pending[i] = i
+	}
+	var errs []error
+	for attempt := 0; attempt < attempts && len(pending) > 0; attempt++ {
+		errs = gr.goN(len(pending), func(j int) error {
+			return fn(pending[j])
+		})
+		failed := make([]int, 0, len(pending))
+		for j, err := range errs {
+			if err != nil {
+				failed = append(failed, pending[j])
+			}
+		}
+		pending = failed
+	}
+	return errors.Joins(errs...)
+}
+
+// GoNUntil is the same as [GoN] but stops once any call of fn returns true, for example when a search finds a match.
+// No more indices are launched, and indices that were launched but have not started yet are skipped.
+// Calls of fn that are already running are not interrupted.
+// It returns whether any call of fn returned true.
+func GoNUntil(n int, fn func(int) (bool, error)) (bool, []error) {
+	return GoConcurrent().GoNUntil(n, fn)
+}
+
+// The same as [GoNUntil] but with go routine launching configured by a GoRoutine.
+// This is most useful with a GoRoutine that limits the number of go routines running at once.
+func (gr GoRoutine) GoNUntil(n int, fn func(int) (bool, error)) (bool, []error) {
+	var done atomic.Bool
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n && !done.Load(); i++ {
+		wg.Add(1)
+		gr(func() error {
+			defer wg.Done()
+			if done.Load() {
 				return nil
+			}
+			errs[i] = Recovered(func() error {
+				found, err := fn(i)
+				if found {
+					done.Store(true)
+				}
+				return err
 			})
+			return errs[i]
 		})
 	}
 	wg.Wait()
+	return done.Load(), errors.Joins(errs...)
+}
+
+// FailedItem is an item that [GoEachDLQ] could not process.
+type FailedItem[T any] struct {
+	Index    int
+	Item     T
+	Err      error
+	Attempts int
+}
+
+// GoEachDLQ is the same as [GoEach] but tries each item up to attempts times
+// and returns the items that never succeeded along with their last error.
+// As with [GoNRetry], attempts less than 1 is the same as 1.
+// The failed items can then be persisted for later reprocessing (a dead letter queue).
+// The failed items are in the order of all.
+func GoEachDLQ[T any](all []T, attempts int, fn func(T) error) []FailedItem[T] {
+	tries := make([]int, len(all))
+	lastErrs := make([]error, len(all))
+	_ = GoNRetry(len(all), attempts, func(i int) error {
+		tries[i]++
+		lastErrs[i] = Recovered(func() error { return fn(all[i]) })
+		return lastErrs[i]
+	})
+
+	var failed []FailedItem[T]
+	for i, err := range lastErrs {
+		if err != nil {
+			failed = append(failed, FailedItem[T]{Index: i, Item: all[i], Err: err, Attempts: tries[i]})
+		}
+	}
+	return failed
+}
+
+// ItemReport is the outcome of running a single index with [GoNReport].
+type ItemReport struct {
+	Err      error
+	Start    time.Time
+	Duration time.Duration
+}
+
+// Report is the outcome of [GoNReport].
+// Items[i] is the outcome of fn(i).
+type Report struct {
+	Items     []ItemReport
+	Succeeded int
+	Failed    int
+}
+
+// Errors returns the errors of the failed items.
+// If no errors occurred, nil will be returned.
+func (r Report) Errors() []error {
+	errs := make([]error, len(r.Items))
+	for i, item := range r.Items {
+		errs[i] = item.Err
+	}
 	return errors.Joins(errs...)
 }
 
+// Durations returns the duration of each index: durations[i] is the duration of fn(i).
+func (r Report) Durations() []time.Duration {
+	durations := make([]time.Duration, len(r.Items))
+	for i, item := range r.Items {
+		durations[i] = item.Duration
+	}
+	return durations
+}
+
+// Slowest returns the indices of the k items that took the longest, slowest first.
+// k is clamped to the number of items, and none are returned if k is not positive.
+func (r Report) Slowest(k int) []int {
+	indices := make([]int, len(r.Items))
+	for i := range indices {
+		indices[i] = i
+	}
+	slices.SortStableFunc(indices, func(a, b int) int {
+		return cmp.Compare(r.Items[b].Duration, r.Items[a].Duration)
+	})
+	return indices[:max(0, min(k, len(indices)))]
+}
+
+// GoNReport is the same as [GoN] but returns a [Report] with the error, start time, and duration of each index.
+func GoNReport(n int, fn func(int) error) Report {
+	return GoConcurrent().GoNReport(n, fn)
+}
+
+// The same as [GoNReport] but with go routine launching configured by a GoRoutine.
+func (gr GoRoutine) GoNReport(n int, fn func(int) error) Report {
+	items := make([]ItemReport, n)
+	errs := gr.goN(n, func(i int) error {
+		items[i].Start = time.Now()
+		defer func() { items[i].Duration = time.Since(items[i].Start) }()
+		return fn(i)
+	})
+	report := Report{Items: items}
+	for i, err := range errs {
+		items[i].Err = err
+		if err == nil {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	return report
+}
+
 // The same as [GoEach] but with go routine launching configured by a GoRoutine.
 //
 // [GoEach] uses generics, so it cannot be called directly as a method.
@@ -132,39 +660,117 @@ func TrySend[T any](c chan<- T, obj T) bool {
 	}
 }
 
-// UnboundedChan transfers its contents into an unbounded slice
-// Close the channel and retrieve the slice data with Drain()
-type UnboundedChan[T any] struct {
-	chanT  chan T
-	sliceT []T
-	done   chan struct{}
+// RecvTimeout receives from a channel, waiting at most d.
+// received is false if the channel is closed or the timeout passed, in which case timedOut is true.
+func RecvTimeout[T any](c <-chan T, d time.Duration) (receivedObject T, received bool, timedOut bool) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case receivedObject, received = <-c:
+		return receivedObject, received, false
+	case <-timer.C:
+		return receivedObject, false, true
+	}
+}
+
+// SendTimeout sends to a channel, waiting at most d.
+// It returns false if the timeout passed before the send happened.
+// Unlike selecting on time.After, the timer is stopped as soon as the send happens.
+func SendTimeout[T any](c chan<- T, obj T, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case c <- obj:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// SendContext sends to a channel, waiting until the send happens or ctx is done.
+// It returns the cause of ctx if ctx is done first.
+func SendContext[T any](ctx context.Context, c chan<- T, obj T) error {
+	select {
+	case c <- obj:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// RecvContext receives from a channel, waiting until an item is received or ctx is done.
+// It returns the cause of ctx if ctx is done first, or [ErrClosed] if the channel is closed.
+func RecvContext[T any](ctx context.Context, c <-chan T) (T, error) {
+	select {
+	case obj, ok := <-c:
+		if !ok {
+			return obj, ErrClosed
+		}
+		return obj, nil
+	case <-ctx.Done():
+		var zero T
+		return zero, context.Cause(ctx)
+	}
 }
 
-func (uc UnboundedChan[T]) Send(x T) {
-	uc.chanT <- x
+// Select2 receives from whichever of two channels of different types is ready first, waiting until ctx is done.
+// which is the index of the channel received from: 0 for a and 1 for b, and the received item is set in the matching result.
+// It returns [ErrClosed] if the channel received from is closed,
+// or the cause of ctx with which set to -1 if ctx is done first.
+func Select2[A any, B any](ctx context.Context, a <-chan A, b <-chan B) (itemA A, itemB B, which int, err error) {
+	var ok bool
+	select {
+	case itemA, ok = <-a:
+		which = 0
+	case itemB, ok = <-b:
+		which = 1
+	case <-ctx.Done():
+		return itemA, itemB, -1, context.Cause(ctx)
+	}
+	if !ok {
+		err = ErrClosed
+	}
+	return itemA, itemB, which, err
 }
 
-func (uc UnboundedChan[T]) Drain() []T {
-	close(uc.chanT)
-	<-uc.done
-	return uc.sliceT
+// Select3 is the same as [Select2] for three channels: which is 2 for c.
+func Select3[A any, B any, C any](ctx context.Context, a <-chan A, b <-chan B, c <-chan C) (itemA A, itemB B, itemC C, which int, err error) {
+	var ok bool
+	select {
+	case itemA, ok = <-a:
+		which = 0
+	case itemB, ok = <-b:
+		which = 1
+	case itemC, ok = <-c:
+		which = 2
+	case <-ctx.Done():
+		return itemA, itemB, itemC, -1, context.Cause(ctx)
+	}
+	if !ok {
+		err = ErrClosed
+	}
+	return itemA, itemB, itemC, which, err
 }
 
-// NewUnboundedChan create an UnboundedChan that transfers its contents into an unbounded slice
-func NewUnboundedChan[T any]() UnboundedChan[T] {
-	chanSize := 10
-	uc := UnboundedChan[T]{
-		chanT:  make(chan T, chanSize),
-		sliceT: make([]T, 0, chanSize),
-		done:   make(chan struct{}),
+// SafeClose closes a channel, returning false instead of panicking if the channel is already closed.
+// This makes shutdown safe when multiple producers may close the same channel.
+// Sending to the channel after it is closed still panics.
+// Like close, it panics for a nil channel.
+func SafeClose[T any](c chan<- T) (closed bool) {
+	if c == nil {
+		panic("concurrent: close of nil channel")
 	}
-	go func() {
-		defer func() {
-			uc.done <- struct{}{}
-		}()
-		for x := range uc.chanT {
-			uc.sliceT = append(uc.sliceT, x)
+	defer func() {
+		if recover() != nil {
+			closed = false
 		}
 	}()
-	return uc
+	close(c)
+	return true
+}
+
+// CloseOnce returns a function that closes the channel the first time it is called and does nothing after.
+// Give it to all the producers that may close the channel so that only the first one closes it.
+func CloseOnce[T any](c chan<- T) func() {
+	return sync.OnceFunc(func() { close(c) })
 }
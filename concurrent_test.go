@@ -3,9 +3,16 @@ package concurrent_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gregwebs/go-concurrent"
+	"github.com/gregwebs/go-recovery"
 	"github.com/shoenig/test/must"
 )
 
@@ -31,6 +38,32 @@ func TestGoN(t *testing.T) {
 	must.True(t, tracked[0])
 }
 
+func TestGoNPanic(t *testing.T) {
+	errPanic := errors.New("panic value")
+	errs := concurrent.GoN(2, func(i int) error {
+		if i == 0 {
+			panic(errPanic)
+		}
+		panic(i)
+	})
+	must.Len(t, 2, errs)
+
+	// the original panic value is preserved
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(errs[0], &pe))
+	must.Eq[any](t, errPanic, pe.Value())
+	must.ErrorIs(t, errs[0], errPanic)
+	must.True(t, errors.As(errs[1], &pe))
+	must.Eq[any](t, 1, pe.Value())
+	var rpe recovery.PanicError
+	must.True(t, errors.As(errs[1], &rpe))
+	must.Eq[any](t, 1, rpe.Panic)
+
+	// the stack trace points to the panic
+	must.StrContains(t, fmt.Sprintf("%+v", errs[1]), "TestGoNPanic")
+	must.StrNotContains(t, fmt.Sprintf("%v", errs[1]), "TestGoNPanic")
+}
+
 func TestGoNSerials(t *testing.T) {
 	var err []error
 	gr := concurrent.GoSerial()
@@ -54,6 +87,156 @@ func TestGoNSerials(t *testing.T) {
 	must.True(t, tracked[0])
 }
 
+func TestGoNRetry(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	var calls [4]int32
+	err := concurrent.GoNRetry(4, 3, func(i int) error {
+		n := atomic.AddInt32(&calls[i], 1)
+		if int(n) <= i {
+			return errFlaky
+		}
+		return nil
+	})
+	// index 3 needs 4 attempts
+	must.Eq(t, []error{errFlaky}, err)
+	must.Eq(t, [4]int32{1, 2, 3, 3}, calls)
+
+	calls = [4]int32{}
+	err = concurrent.GoSerial().GoNRetry(4, 4, func(i int) error {
+		calls[i]++
+		if int(calls[i]) <= i {
+			return errFlaky
+		}
+		return nil
+	})
+	must.Nil(t, err)
+	must.Eq(t, [4]int32{1, 2, 3, 4}, calls)
+
+	err = concurrent.GoNRetry(2, 2, func(i int) error { panic("always") })
+	must.Len(t, 2, err)
+
+	// fn is called once rather than reporting success for work that never ran
+	for _, attempts := range []int{0, -1} {
+		var calls int32
+		err = concurrent.GoNRetry(2, attempts, func(i int) error {
+			atomic.AddInt32(&calls, 1)
+			return errFlaky
+		})
+		must.Len(t, 2, err)
+		must.Eq(t, 2, atomic.LoadInt32(&calls))
+	}
+}
+
+func TestGoEachDLQ(t *testing.T) {
+	must.Nil(t, concurrent.GoEachDLQ([]int{1, 2}, 3, func(_ int) error { return nil }))
+
+	errBad := errors.New("bad")
+	var flakyCalls int32
+	failed := concurrent.GoEachDLQ([]string{"ok", "bad", "flaky", "panic"}, 2, func(item string) error {
+		switch item {
+		case "bad":
+			return errBad
+		case "flaky":
+			if atomic.AddInt32(&flakyCalls, 1) == 1 {
+				return errBad
+			}
+		case "panic":
+			panic(item)
+		}
+		return nil
+	})
+	must.Len(t, 2, failed)
+	must.Eq(t, 1, failed[0].Index)
+	must.Eq(t, "bad", failed[0].Item)
+	must.Eq(t, errBad, failed[0].Err)
+	must.Eq(t, 2, failed[0].Attempts)
+	must.Eq(t, "panic", failed[1].Item)
+	must.Eq(t, 2, failed[1].Attempts)
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(failed[1].Err, &pe))
+
+	// each item is tried once rather than being lost
+	failed = concurrent.GoEachDLQ([]string{"bad"}, 0, func(string) error { return errBad })
+	must.Len(t, 1, failed)
+	must.Eq(t, 1, failed[0].Attempts)
+}
+
+func TestGoNUntil(t *testing.T) {
+	found, err := concurrent.GoNUntil(0, func(_ int) (bool, error) { return true, nil })
+	must.False(t, found)
+	must.Nil(t, err)
+
+	var calls []int
+	found, err = concurrent.GoSerial().GoNUntil(10, func(i int) (bool, error) {
+		calls = append(calls, i)
+		return i == 3, nil
+	})
+	must.True(t, found)
+	must.Nil(t, err)
+	must.Eq(t, []int{0, 1, 2, 3}, calls)
+
+	errBad := errors.New("bad")
+	var count int32
+	found, err = concurrent.GoNUntil(10, func(i int) (bool, error) {
+		atomic.AddInt32(&count, 1)
+		if i == 4 {
+			return false, errBad
+		}
+		return false, nil
+	})
+	must.False(t, found)
+	must.Eq(t, []error{errBad}, err)
+	must.Eq(t, 10, atomic.LoadInt32(&count))
+}
+
+func TestGoNReport(t *testing.T) {
+	report := concurrent.GoNReport(0, func(_ int) error { return nil })
+	must.Len(t, 0, report.Items)
+	must.Nil(t, report.Errors())
+
+	errOdd := errors.New("odd")
+	before := time.Now()
+	report = concurrent.GoNReport(4, func(i int) error {
+		time.Sleep(time.Duration(i) * time.Millisecond)
+		if i%2 == 1 {
+			return errOdd
+		}
+		if i == 2 {
+			panic("two")
+		}
+		return nil
+	})
+	must.Len(t, 4, report.Items)
+	must.Eq(t, 1, report.Succeeded)
+	must.Eq(t, 3, report.Failed)
+	must.Len(t, 3, report.Errors())
+	must.Nil(t, report.Items[0].Err)
+	must.Eq(t, errOdd, report.Items[1].Err)
+	must.NotNil(t, report.Items[2].Err)
+	for i, item := range report.Items {
+		must.False(t, item.Start.Before(before))
+		must.GreaterEq(t, time.Duration(i)*time.Millisecond, item.Duration)
+	}
+}
+
+func TestReportDurations(t *testing.T) {
+	report := concurrent.Report{Items: []concurrent.ItemReport{
+		{Duration: 2 * time.Second},
+		{Duration: 5 * time.Second},
+		{Duration: 1 * time.Second},
+	}}
+	must.Eq(t, []time.Duration{2 * time.Second, 5 * time.Second, time.Second}, report.Durations())
+	must.Eq(t, []int{1, 0}, report.Slowest(2))
+	must.Eq(t, []int{1, 0, 2}, report.Slowest(10))
+	must.SliceEmpty(t, report.Slowest(-1))
+
+	report = concurrent.GoNReport(3, func(i int) error {
+		time.Sleep(time.Duration(i*5) * time.Millisecond)
+		return nil
+	})
+	must.Eq(t, []int{2}, report.Slowest(1))
+}
+
 func TestGoEach(t *testing.T) {
 	var err []error
 	tracked := make([]bool, 10)
@@ -75,6 +258,93 @@ func TestGoEach(t *testing.T) {
 	must.True(t, tracked[0])
 }
 
+func TestGoNOrdered(t *testing.T) {
+	results, err := concurrent.GoNOrdered(0, func(i int) (int, error) { return i, nil })
+	must.Nil(t, err)
+	must.Len(t, 0, results)
+
+	// later indices finish first
+	const n = 20
+	results, err = concurrent.GoNOrdered(n, func(i int) (int, error) {
+		time.Sleep(time.Duration(n-i) * 100 * time.Microsecond)
+		return i * i, nil
+	})
+	must.Nil(t, err)
+	for i, result := range results {
+		must.Eq(t, i*i, result)
+	}
+
+	errBad := errors.New("bad")
+	strs, err := concurrent.GoNOrdered(3, func(i int) (string, error) {
+		if i == 1 {
+			return "ignored", errBad
+		}
+		return fmt.Sprint(i), nil
+	})
+	must.Eq(t, []error{errBad}, err)
+	must.Eq(t, []string{"0", "", "2"}, strs)
+}
+
+func TestGoEachKV(t *testing.T) {
+	m := map[string]int{"a": 1, "b": 2, "c": 3}
+	var sum int32
+	err := concurrent.GoEachKV(m, func(_ string, v int) error {
+		atomic.AddInt32(&sum, int32(v))
+		return nil
+	})
+	must.Nil(t, err)
+	must.Eq(t, 6, atomic.LoadInt32(&sum))
+
+	errBad := errors.New("bad")
+	err = concurrent.GoEachKV(m, func(k string, _ int) error {
+		switch k {
+		case "b":
+			return errBad
+		case "c":
+			panic("c")
+		}
+		return nil
+	})
+	must.MapLen(t, 2, err)
+	must.Eq(t, errBad, err["b"])
+	must.NotNil(t, err["c"])
+	must.MapNotContainsKey(t, err, "a")
+}
+
+func TestGoEachCheckpoint(t *testing.T) {
+	items := []string{"a", "b", "c", "d"}
+	errB := errors.New("b failed")
+	var completed []int
+	var ran []string
+	var mu sync.Mutex
+	fn := func(failB bool) func(string) error {
+		return func(item string) error {
+			mu.Lock()
+			ran = append(ran, item)
+			mu.Unlock()
+			if failB && item == "b" {
+				return errB
+			}
+			return nil
+		}
+	}
+	checkpoint := func(i int) { completed = append(completed, i) }
+
+	err := concurrent.GoEachCheckpoint(items, nil, checkpoint, fn(true))
+	must.Eq(t, []error{errB}, err)
+	slices.Sort(completed)
+	must.Eq(t, []int{0, 2, 3}, completed)
+	must.Len(t, 4, ran)
+
+	// resume: only the failed item runs again
+	ran = nil
+	err = concurrent.GoEachCheckpoint(items, completed, checkpoint, fn(false))
+	must.Nil(t, err)
+	must.Eq(t, []string{"b"}, ran)
+	slices.Sort(completed)
+	must.Eq(t, []int{0, 1, 2, 3}, completed)
+}
+
 func TestGoEachSerial(t *testing.T) {
 	var err []error
 	tracked := make([]bool, 10)
@@ -97,6 +367,251 @@ func TestGoEachSerial(t *testing.T) {
 	must.True(t, tracked[0])
 }
 
+func TestGoNAuto(t *testing.T) {
+	limit := int32(runtime.GOMAXPROCS(0))
+	var active int32
+	var maxActive int32
+	tracked := make([]bool, 100)
+	err := concurrent.GoNAuto(len(tracked), func(i int) error {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		tracked[i] = true
+		atomic.AddInt32(&active, -1)
+		return nil
+	})
+	must.Nil(t, err)
+	must.LessEq(t, limit, atomic.LoadInt32(&maxActive))
+	for _, done := range tracked {
+		must.True(t, done)
+	}
+}
+
+func TestGoNLimit(t *testing.T) {
+	must.Nil(t, concurrent.GoNLimit(0, 4, func(_ int) error { return nil }))
+
+	tracked := make([]bool, 7)
+	err := concurrent.GoNLimit(len(tracked), 3, func(i int) error {
+		tracked[i] = true
+		if i == 5 {
+			panic("five")
+		}
+		return nil
+	})
+	must.Len(t, 1, err)
+	for _, done := range tracked {
+		must.True(t, done)
+	}
+
+	// Index 0 is slow: it waits until the other indices of its worker are done.
+	// They can only be done by being stolen by the other worker.
+	const n = 10
+	var finished int32
+	err = concurrent.GoNLimit(n, 2, func(i int) error {
+		if i == 0 {
+			deadline := time.Now().Add(5 * time.Second)
+			for atomic.LoadInt32(&finished) < n-1 {
+				if time.Now().After(deadline) {
+					return errors.New("indices were not stolen")
+				}
+				time.Sleep(time.Millisecond)
+			}
+			return nil
+		}
+		atomic.AddInt32(&finished, 1)
+		return nil
+	})
+	must.Nil(t, err)
+}
+
+func TestGoGrid(t *testing.T) {
+	must.Nil(t, concurrent.GoGrid(0, 5, 2, func(_, _ int) error { return nil }))
+
+	var cells [3][4]int32
+	var active, maxActive int32
+	err := concurrent.GoGrid(3, 4, 2, func(r, c int) error {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		atomic.AddInt32(&cells[r][c], 1)
+		atomic.AddInt32(&active, -1)
+		if r == 2 && c == 3 {
+			return fmt.Errorf("cell %d,%d", r, c)
+		}
+		return nil
+	})
+	must.Len(t, 1, err)
+	must.EqError(t, err[0], "cell 2,3")
+	must.LessEq(t, 2, atomic.LoadInt32(&maxActive))
+	for r := range cells {
+		for c := range cells[r] {
+			must.Eq(t, 1, cells[r][c])
+		}
+	}
+}
+
+func TestGoDynamic(t *testing.T) {
+	must.Nil(t, concurrent.GoDynamic(2, []int{}, func(_ int, _ func(int)) error { return nil }))
+
+	// walk a binary tree of depth 4 whose nodes are numbered like a heap
+	var visited int32
+	err := concurrent.GoDynamic(3, []int{1}, func(node int, emit func(int)) error {
+		atomic.AddInt32(&visited, 1)
+		if node < 8 {
+			emit(2 * node)
+			emit(2*node + 1)
+		}
+		return nil
+	})
+	must.Nil(t, err)
+	must.Eq(t, 15, atomic.LoadInt32(&visited))
+
+	errBad := errors.New("bad")
+	err = concurrent.GoDynamic(2, []int{1, 2, 3}, func(x int, emit func(int)) error {
+		if x == 2 {
+			return errBad
+		}
+		if x == 3 {
+			emit(4)
+		}
+		if x == 4 {
+			panic("four")
+		}
+		return nil
+	})
+	must.Len(t, 2, err)
+}
+
+func TestGoEachAuto(t *testing.T) {
+	items := []int{1, 2, 3}
+	var sum int32
+	err := concurrent.GoEachAuto(items, func(x int) error {
+		atomic.AddInt32(&sum, int32(x))
+		return nil
+	})
+	must.Nil(t, err)
+	must.Eq(t, 6, atomic.LoadInt32(&sum))
+
+	errBad := errors.New("bad")
+	err = concurrent.GoEachAuto(items, func(x int) error {
+		if x == 2 {
+			return errBad
+		}
+		return nil
+	})
+	must.Eq(t, []error{errBad}, err)
+}
+
+func TestGoReduce(t *testing.T) {
+	square := func(x int) (int, error) { return x * x, nil }
+	sum := func(a, b int) int { return a + b }
+	total, err := concurrent.GoReduce([]int{1, 2, 3}, square, sum)
+	must.Nil(t, err)
+	must.Eq(t, 14, total)
+
+	total, err = concurrent.GoReduce([]int{}, square, sum)
+	must.Nil(t, err)
+	must.Eq(t, 0, total)
+
+	// the combine order is the order of the items
+	words := []string{"a", "b", "c", "d", "e"}
+	same := func(s string) (string, error) { return s, nil }
+	concat := func(a, b string) string { return a + b }
+	joined, err := concurrent.GoReduce(words, same, concat)
+	must.Nil(t, err)
+	must.Eq(t, "abcde", joined)
+
+	errOdd := errors.New("odd")
+	evenOnly := func(x int) (int, error) {
+		if x%2 == 1 {
+			return 0, errOdd
+		}
+		return x, nil
+	}
+	total, err = concurrent.GoReduce([]int{1, 2, 3, 4}, evenOnly, sum)
+	must.Eq(t, []error{errOdd, errOdd}, err)
+	must.Eq(t, 6, total)
+}
+
+func TestGoFilter(t *testing.T) {
+	isEven := func(x int) (bool, error) { return x%2 == 0, nil }
+	kept, err := concurrent.GoFilter([]int{1, 2, 3, 4, 5, 6}, isEven)
+	must.Nil(t, err)
+	must.Eq(t, []int{2, 4, 6}, kept)
+
+	kept, err = concurrent.GoFilter([]int{}, isEven)
+	must.Nil(t, err)
+	must.Len(t, 0, kept)
+
+	errThree := errors.New("three")
+	failThree := func(x int) (bool, error) {
+		if x == 3 {
+			return true, errThree
+		}
+		return true, nil
+	}
+	kept, err = concurrent.GoFilter([]int{1, 2, 3, 4}, failThree)
+	must.Eq(t, []error{errThree}, err)
+	must.Eq(t, []int{1, 2, 4}, kept)
+}
+
+func TestGoConsume(t *testing.T) {
+	ctx := context.Background()
+	ch := make(chan int)
+	go func() {
+		for i := 1; i <= 10; i++ {
+			ch <- i
+		}
+		close(ch)
+	}()
+	var sum int32
+	err := concurrent.GoConsume(ctx, ch, 3, func(x int) error {
+		atomic.AddInt32(&sum, int32(x))
+		return nil
+	})
+	must.Nil(t, err)
+	must.Eq(t, 55, atomic.LoadInt32(&sum))
+
+	errBad := errors.New("bad")
+	ch = make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	close(ch)
+	err = concurrent.GoConsume(ctx, ch, 2, func(x int) error {
+		if x == 2 {
+			return errBad
+		}
+		if x == 3 {
+			panic("three")
+		}
+		return nil
+	})
+	must.Len(t, 2, err)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = concurrent.GoConsume(ctx, make(chan int), 2, func(x int) error { return nil })
+	must.Eq(t, []error{context.Canceled}, err)
+
+	// without workers the channel would never be consumed
+	for _, workers := range []int{0, -1} {
+		err := concurrent.Recovered(func() error {
+			concurrent.GoConsume(ctx, make(chan int), workers, func(x int) error { return nil })
+			return nil
+		})
+		must.Error(t, err)
+	}
+}
+
 func TestChannelMerge(t *testing.T) {
 	{
 		c1 := make(chan error)
@@ -127,6 +642,101 @@ func TestChannelMerge(t *testing.T) {
 	}
 }
 
+func TestSendRecvContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	c := make(chan int)
+	must.ErrorIs(t, concurrent.SendContext(ctx, c, 1), context.DeadlineExceeded)
+	_, err := concurrent.RecvContext(ctx, c)
+	must.ErrorIs(t, err, context.DeadlineExceeded)
+
+	go func() {
+		must.NoError(t, concurrent.SendContext(context.Background(), c, 2))
+		close(c)
+	}()
+	x, err := concurrent.RecvContext(context.Background(), c)
+	must.NoError(t, err)
+	must.Eq(t, 2, x)
+	_, err = concurrent.RecvContext(context.Background(), c)
+	must.ErrorIs(t, err, concurrent.ErrClosed)
+}
+
+func TestRecvTimeout(t *testing.T) {
+	c := make(chan int, 1)
+	_, received, timedOut := concurrent.RecvTimeout(c, time.Millisecond)
+	must.False(t, received)
+	must.True(t, timedOut)
+	c <- 1
+	x, received, timedOut := concurrent.RecvTimeout(c, time.Hour)
+	must.True(t, received)
+	must.False(t, timedOut)
+	must.Eq(t, 1, x)
+	close(c)
+	_, received, timedOut = concurrent.RecvTimeout(c, time.Hour)
+	must.False(t, received)
+	must.False(t, timedOut)
+}
+
+func TestSendTimeout(t *testing.T) {
+	c := make(chan int, 1)
+	must.True(t, concurrent.SendTimeout(c, 1, time.Hour))
+	must.False(t, concurrent.SendTimeout(c, 2, time.Millisecond))
+	must.Eq(t, 1, <-c)
+}
+
+func TestSelect2(t *testing.T) {
+	ctx := context.Background()
+	ints := make(chan int, 1)
+	strs := make(chan string, 1)
+	strs <- "a"
+	i, str, which, err := concurrent.Select2(ctx, ints, strs)
+	must.NoError(t, err)
+	must.Eq(t, 1, which)
+	must.Eq(t, "a", str)
+	must.Eq(t, 0, i)
+
+	close(ints)
+	_, _, which, err = concurrent.Select2(ctx, ints, strs)
+	must.ErrorIs(t, err, concurrent.ErrClosed)
+	must.Eq(t, 0, which)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _, which, err = concurrent.Select2(ctx, make(chan int), strs)
+	must.ErrorIs(t, err, context.Canceled)
+	must.Eq(t, -1, which)
+}
+
+func TestSelect3(t *testing.T) {
+	ctx := context.Background()
+	durations := make(chan time.Duration, 1)
+	durations <- time.Second
+	_, _, d, which, err := concurrent.Select3(ctx, make(chan int), make(chan string), durations)
+	must.NoError(t, err)
+	must.Eq(t, 2, which)
+	must.Eq(t, time.Second, d)
+}
+
+func TestSafeClose(t *testing.T) {
+	c := make(chan int)
+	must.True(t, concurrent.SafeClose(c))
+	must.False(t, concurrent.SafeClose(c))
+	_, ok := <-c
+	must.False(t, ok)
+}
+
+func TestCloseOnce(t *testing.T) {
+	c := make(chan int)
+	closeC := concurrent.CloseOnce(c)
+	errs := concurrent.GoN(10, func(int) error {
+		closeC()
+		return nil
+	})
+	must.Nil(t, errs)
+	_, ok := <-c
+	must.False(t, ok)
+}
+
 func TestGroup(t *testing.T) {
 	ctx := context.Background()
 	var err []error
@@ -154,3 +764,37 @@ func TestGroup(t *testing.T) {
 	must.True(t, tracked[1])
 	must.True(t, tracked[0])
 }
+
+func TestGroupPanicHandler(t *testing.T) {
+	errReported := errors.New("reported")
+	var reported []any
+	group, _ := concurrent.NewGroupContext(context.Background())
+	group.SetGoRoutine(concurrent.GoSerial())
+	group.SetPanicHandler(func(recovered any) error {
+		reported = append(reported, recovered)
+		if recovered == "swallow" {
+			return nil
+		}
+		return errReported
+	})
+	group.Go(func() error { panic("swallow") })
+	must.Nil(t, group.Wait())
+	group.Go(func() error { panic("report") })
+	must.Eq(t, []error{errReported}, group.Wait())
+	must.Eq(t, []any{"swallow", "report"}, reported)
+
+	// the handler is not called for returned errors
+	errReturned := errors.New("returned")
+	group.Go(func() error { return errReturned })
+	must.Eq(t, []error{errReturned}, group.Wait())
+	must.Len(t, 2, reported)
+
+	// a panic in the handler is converted to an error
+	group.SetPanicHandler(func(recovered any) error { panic("handler") })
+	group.Go(func() error { panic("report") })
+	errs := group.Wait()
+	must.Len(t, 1, errs)
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(errs[0], &pe))
+	must.Eq[any](t, "handler", pe.Value())
+}
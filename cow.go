@@ -0,0 +1,115 @@
+package concurrent
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// COWSlice is a copy-on-write slice for data that is read often and written rarely.
+// Readers get the current slice without locking.
+// Writers copy the slice, modify the copy, and then atomically replace the current slice.
+// Writers are serialized so that no write is lost.
+//
+// The zero value is an empty COWSlice ready to use.
+// A COWSlice must not be copied after first use.
+type COWSlice[T any] struct {
+	mu sync.Mutex
+	p  atomic.Pointer[[]T]
+}
+
+// Load returns the current slice.
+// The slice is shared with other readers and must not be modified.
+func (s *COWSlice[T]) Load() []T {
+	if p := s.p.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// Store replaces the current slice with items.
+// items must not be modified afterwards.
+func (s *COWSlice[T]) Store(items []T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.p.Store(&items)
+}
+
+// Update calls fn with a copy of the current slice and makes the slice that fn returns the current slice.
+func (s *COWSlice[T]) Update(fn func([]T) []T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := fn(slices.Clone(s.Load()))
+	s.p.Store(&items)
+}
+
+// Append adds items to a copy of the current slice and makes it the current slice.
+func (s *COWSlice[T]) Append(items ...T) {
+	s.Update(func(current []T) []T {
+		return append(current, items...)
+	})
+}
+
+// COWMap is a copy-on-write map for data that is read often and written rarely,
+// such as configuration or routing tables.
+// Readers get the current map without locking.
+// Writers copy the map, modify the copy, and then atomically replace the current map.
+// Writers are serialized so that no write is lost.
+//
+// The zero value is an empty COWMap ready to use.
+// A COWMap must not be copied after first use.
+type COWMap[K comparable, V any] struct {
+	mu sync.Mutex
+	p  atomic.Pointer[map[K]V]
+}
+
+// Load returns the current map.
+// The map is shared with other readers and must not be modified.
+func (m *COWMap[K, V]) Load() map[K]V {
+	if p := m.p.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// Get returns the value stored for k in the current map.
+// It returns false if there is no value for k.
+func (m *COWMap[K, V]) Get(k K) (V, bool) {
+	v, ok := m.Load()[k]
+	return v, ok
+}
+
+// Store replaces the current map with items.
+// items must not be modified afterwards.
+func (m *COWMap[K, V]) Store(items map[K]V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.p.Store(&items)
+}
+
+// Update calls fn with a copy of the current map and makes the copy the current map.
+func (m *COWMap[K, V]) Update(fn func(map[K]V)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	items := maps.Clone(m.Load())
+	if items == nil {
+		items = make(map[K]V)
+	}
+	fn(items)
+	m.p.Store(&items)
+}
+
+// Set stores v for k in a copy of the current map and makes it the current map.
+func (m *COWMap[K, V]) Set(k K, v V) {
+	m.Update(func(items map[K]V) {
+		items[k] = v
+	})
+}
+
+// Delete removes the value for k from a copy of the current map and makes it the current map.
+func (m *COWMap[K, V]) Delete(k K) {
+	m.Update(func(items map[K]V) {
+		delete(items, k)
+	})
+}
@@ -0,0 +1,60 @@
+package concurrent_test
+
+import (
+	"testing"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestCOWSlice(t *testing.T) {
+	var s concurrent.COWSlice[int]
+	must.Nil(t, s.Load())
+	s.Append(1, 2)
+	before := s.Load()
+	s.Append(3)
+	must.Eq(t, []int{1, 2}, before)
+	must.Eq(t, []int{1, 2, 3}, s.Load())
+
+	s.Update(func(items []int) []int {
+		items[0] = 10
+		return items[:2]
+	})
+	must.Eq(t, []int{10, 2}, s.Load())
+	s.Store([]int{4})
+	must.Eq(t, []int{4}, s.Load())
+
+	errs := concurrent.GoN(100, func(i int) error {
+		s.Append(i)
+		_ = len(s.Load())
+		return nil
+	})
+	must.Nil(t, errs)
+	must.SliceLen(t, 101, s.Load())
+}
+
+func TestCOWMap(t *testing.T) {
+	var m concurrent.COWMap[string, int]
+	_, ok := m.Get("a")
+	must.False(t, ok)
+	m.Set("a", 1)
+	before := m.Load()
+	m.Set("b", 2)
+	m.Delete("a")
+	must.Eq(t, map[string]int{"a": 1}, before)
+	must.Eq(t, map[string]int{"b": 2}, m.Load())
+	v, ok := m.Get("b")
+	must.True(t, ok)
+	must.Eq(t, 2, v)
+
+	m.Store(map[string]int{"c": 3})
+	errs := concurrent.GoN(100, func(i int) error {
+		m.Update(func(items map[string]int) {
+			items["c"]++
+		})
+		m.Get("c")
+		return nil
+	})
+	must.Nil(t, errs)
+	must.Eq(t, map[string]int{"c": 103}, m.Load())
+}
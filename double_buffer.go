@@ -0,0 +1,45 @@
+package concurrent
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DoubleBuffer holds two values of T: the current one that is read and the next one that is being built.
+// Readers get the current value without locking
+// while a producer prepares the next value, which becomes current at Swap.
+//
+// Swap builds into the value that was current before the previous Swap,
+// so a reader must be done with the value returned by Read before the second Swap after it.
+// Use [COWSlice], [COWMap], or an atomic.Pointer to a new value when readers hold on to values longer.
+//
+// The zero value holds a zero T and is ready to use.
+// A DoubleBuffer must not be copied after first use.
+type DoubleBuffer[T any] struct {
+	mu      sync.Mutex
+	buffers [2]T
+	current atomic.Pointer[T]
+}
+
+// Read returns the current value.
+// It must not be modified.
+func (d *DoubleBuffer[T]) Read() *T {
+	if p := d.current.Load(); p != nil {
+		return p
+	}
+	return &d.buffers[0]
+}
+
+// Swap calls build to prepare the next value and then makes it the current value.
+// build is given the value from before the previous Swap so that its allocations can be reused.
+// Calls to Swap are serialized.
+func (d *DoubleBuffer[T]) Swap(build func(next *T)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	next := &d.buffers[1]
+	if d.Read() == next {
+		next = &d.buffers[0]
+	}
+	build(next)
+	d.current.Store(next)
+}
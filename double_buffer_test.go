@@ -0,0 +1,48 @@
+package concurrent_test
+
+import (
+	"testing"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestDoubleBuffer(t *testing.T) {
+	var d concurrent.DoubleBuffer[[]int]
+	must.Nil(t, *d.Read())
+	d.Swap(func(next *[]int) {
+		must.Nil(t, *next)
+		*next = append(*next, 1)
+	})
+	first := d.Read()
+	must.Eq(t, []int{1}, *first)
+	d.Swap(func(next *[]int) {
+		must.Nil(t, *next)
+		*next = append(*next, 2)
+	})
+	must.Eq(t, []int{1}, *first)
+	must.Eq(t, []int{2}, *d.Read())
+	d.Swap(func(next *[]int) {
+		// the buffer from two swaps ago is reused
+		must.Eq(t, first, next)
+		*next = append((*next)[:0], 3)
+	})
+	must.Eq(t, []int{3}, *d.Read())
+}
+
+func TestDoubleBufferConcurrent(t *testing.T) {
+	// a Swap can build while readers read the current value
+	var d concurrent.DoubleBuffer[int]
+	errs := concurrent.GoN(100, func(i int) error {
+		if i == 50 {
+			d.Swap(func(next *int) {
+				*next = i
+			})
+		} else {
+			_ = *d.Read()
+		}
+		return nil
+	})
+	must.Nil(t, errs)
+	must.Eq(t, 50, *d.Read())
+}
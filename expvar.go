@@ -0,0 +1,28 @@
+package concurrent
+
+import (
+	"expvar"
+)
+
+// Expvar returns the [UnboundedChan.Stats] as an expvar.Var so that the health of the queue can be published:
+//
+//	expvar.Publish("jobs", uc.Expvar())
+//
+// The stats are read each time the variable is read.
+func (uc UnboundedChan[T]) Expvar() expvar.Var {
+	return expvar.Func(func() any {
+		return uc.Stats()
+	})
+}
+
+// ChannelExpvar returns the number of items buffered in a channel and its capacity as an expvar.Var,
+// to be published with expvar.Publish.
+// They are read each time the variable is read.
+func ChannelExpvar[T any](c <-chan T) expvar.Var {
+	return expvar.Func(func() any {
+		return struct {
+			Depth    int
+			Capacity int
+		}{len(c), cap(c)}
+	})
+}
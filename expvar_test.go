@@ -0,0 +1,24 @@
+package concurrent_test
+
+import (
+	"testing"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestUnboundedChanExpvar(t *testing.T) {
+	uc := concurrent.NewBoundedChan[int](2, concurrent.DropNewest)
+	v := uc.Expvar()
+	must.Eq(t, `{"Depth":0,"MaxDepth":0,"Sent":0,"Received":0,"Dropped":0}`, v.String())
+	uc.SendAll([]int{1, 2, 3})
+	uc.Recv()
+	must.Eq(t, `{"Depth":1,"MaxDepth":2,"Sent":2,"Received":1,"Dropped":1}`, v.String())
+}
+
+func TestChannelExpvar(t *testing.T) {
+	c := make(chan int, 3)
+	v := concurrent.ChannelExpvar(c)
+	c <- 1
+	must.Eq(t, `{"Depth":1,"Capacity":3}`, v.String())
+}
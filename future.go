@@ -0,0 +1,217 @@
+package concurrent
+
+import (
+	"context"
+
+	"github.com/gregwebs/errors"
+)
+
+// Future is the result of a computation running in a go routine, started by [Async].
+// It is a lighter-weight alternative to a [Group] for a single computation with a result.
+type Future[T any] struct {
+	// done is closed once value and err are set
+	done  chan struct{}
+	value T
+	err   error
+	// cancel is nil unless the Future was created by AsyncContext
+	cancel context.CancelFunc
+}
+
+// Async runs fn in a go routine and returns a Future for its result.
+// A panic in fn is recovered and returned as a [*PanicError] by Await.
+func Async[T any](fn func() (T, error)) *Future[T] {
+	f := newFuture[T]()
+	go func() {
+		var value T
+		err := Recovered(func() error {
+			var err error
+			value, err = fn()
+			return err
+		})
+		f.complete(value, err)
+	}()
+	return f
+}
+
+// AsyncContext is the same as [Async] but fn is given a context that is cancelled by [Future.Cancel],
+// so that the computation can be stopped when its result is no longer needed.
+// The context is also cancelled once fn returns.
+func AsyncContext[T any](ctx context.Context, fn func(context.Context) (T, error)) *Future[T] {
+	ctx, cancel := context.WithCancel(ctx)
+	f := Async(func() (T, error) {
+		defer cancel()
+		return fn(ctx)
+	})
+	f.cancel = cancel
+	return f
+}
+
+func newFuture[T any]() *Future[T] {
+	return &Future[T]{done: make(chan struct{})}
+}
+
+// complete must be called exactly once.
+func (f *Future[T]) complete(value T, err error) {
+	f.value = value
+	f.err = err
+	close(f.done)
+}
+
+// Await waits for the computation to finish and returns its result.
+// It returns the cause of ctx if ctx is done first, but the computation continues.
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, context.Cause(ctx)
+	}
+}
+
+// TryGet returns the result without waiting.
+// done is false if the computation has not finished.
+func (f *Future[T]) TryGet() (value T, done bool, err error) {
+	select {
+	case <-f.done:
+		return f.value, true, f.err
+	default:
+		return value, false, nil
+	}
+}
+
+// Done returns a channel that is closed once the computation has finished.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// wait waits for the computation to finish and returns its result.
+func (f *Future[T]) wait() (T, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+// FutureMap returns a Future for the result of fn applied to the result of f.
+// If f fails, fn is not called and the returned Future has the error of f.
+// A panic in fn is recovered and returned as a [*PanicError].
+//
+// This is a function rather than a method because methods cannot have type parameters.
+func FutureMap[T any, R any](f *Future[T], fn func(T) (R, error)) *Future[R] {
+	return Async(func() (R, error) {
+		value, err := f.wait()
+		if err != nil {
+			var zero R
+			return zero, err
+		}
+		return fn(value)
+	})
+}
+
+// FutureThen chains an asynchronous step: it returns a Future for the result of the Future returned by fn,
+// which is given the result of f.
+// If f fails, fn is not called and the returned Future has the error of f.
+// A panic in fn is recovered and returned as a [*PanicError].
+func FutureThen[T any, R any](f *Future[T], fn func(T) *Future[R]) *Future[R] {
+	return Async(func() (R, error) {
+		value, err := f.wait()
+		if err != nil {
+			var zero R
+			return zero, err
+		}
+		return fn(value).wait()
+	})
+}
+
+// Catch returns a Future that recovers from the error of f by calling fn with it.
+// If f succeeds, fn is not called and the returned Future has the result of f.
+// A panic in fn is recovered and returned as a [*PanicError].
+func (f *Future[T]) Catch(fn func(error) (T, error)) *Future[T] {
+	return Async(func() (T, error) {
+		value, err := f.wait()
+		if err == nil {
+			return value, nil
+		}
+		return fn(err)
+	})
+}
+
+// Finally returns a Future with the result of f that calls fn once f finishes, whether it failed or not,
+// for example to release resources.
+// A panic in fn is recovered and returned as a [*PanicError].
+func (f *Future[T]) Finally(fn func()) *Future[T] {
+	return Async(func() (T, error) {
+		value, err := f.wait()
+		fn()
+		return value, err
+	})
+}
+
+// Cancel cancels the context given to the computation of a Future created by [AsyncContext].
+// The Future then finishes with whatever the computation returns.
+// It does nothing for other Futures, including the Futures derived from a Future created by AsyncContext.
+func (f *Future[T]) Cancel() {
+	if f.cancel != nil {
+		f.cancel()
+	}
+}
+
+// FutureAll returns a Future for the results of all the futures, in the same order.
+// It fails with the first error of the futures, in which case the other futures are cancelled with [Future.Cancel].
+func FutureAll[T any](futures ...*Future[T]) *Future[[]T] {
+	return Async(func() ([]T, error) {
+		finished := make(chan int, len(futures))
+		for i, f := range futures {
+			go func() {
+				<-f.done
+				finished <- i
+			}()
+		}
+		values := make([]T, len(futures))
+		for range futures {
+			i := <-finished
+			value, err := futures[i].wait()
+			if err != nil {
+				cancelFutures(futures)
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	})
+}
+
+// FutureAny returns a Future for the first successful result of the futures.
+// The other futures are then cancelled with [Future.Cancel].
+// It only fails if all the futures fail, in which case all the errors are joined.
+func FutureAny[T any](futures ...*Future[T]) *Future[T] {
+	return Async(func() (T, error) {
+		var zero T
+		if len(futures) == 0 {
+			return zero, errors.New("concurrent.FutureAny: no futures given")
+		}
+		finished := make(chan int, len(futures))
+		for i, f := range futures {
+			go func() {
+				<-f.done
+				finished <- i
+			}()
+		}
+		errs := make([]error, len(futures))
+		for range futures {
+			i := <-finished
+			value, err := futures[i].wait()
+			if err == nil {
+				cancelFutures(futures)
+				return value, nil
+			}
+			errs[i] = err
+		}
+		return zero, errors.Join(errs...)
+	})
+}
+
+func cancelFutures[T any](futures []*Future[T]) {
+	for _, f := range futures {
+		f.Cancel()
+	}
+}
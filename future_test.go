@@ -0,0 +1,148 @@
+package concurrent_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestAsync(t *testing.T) {
+	ctx := context.Background()
+	start := make(chan struct{})
+	f := concurrent.Async(func() (int, error) {
+		<-start
+		return 1, nil
+	})
+	_, done, err := f.TryGet()
+	must.False(t, done)
+	must.NoError(t, err)
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	_, err = f.Await(ctxTimeout)
+	must.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(start)
+	<-f.Done()
+	x, done, err := f.TryGet()
+	must.True(t, done)
+	must.NoError(t, err)
+	must.Eq(t, 1, x)
+	x, err = f.Await(ctx)
+	must.NoError(t, err)
+	must.Eq(t, 1, x)
+}
+
+func TestAsyncError(t *testing.T) {
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+	_, err := concurrent.Async(func() (int, error) { return 0, errFailed }).Await(ctx)
+	must.ErrorIs(t, err, errFailed)
+
+	_, err = concurrent.Async(func() (int, error) { panic("boom") }).Await(ctx)
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(err, &pe))
+	must.Eq[any](t, "boom", pe.Value())
+}
+
+func TestFutureChain(t *testing.T) {
+	ctx := context.Background()
+	finished := false
+	f := concurrent.FutureMap(concurrent.Async(func() (int, error) { return 2, nil }), func(x int) (string, error) {
+		return strconv.Itoa(x * 2), nil
+	})
+	f = concurrent.FutureThen(f, func(s string) *concurrent.Future[string] {
+		return concurrent.Async(func() (string, error) { return s + "!", nil })
+	}).Finally(func() { finished = true })
+	s, err := f.Await(ctx)
+	must.NoError(t, err)
+	must.Eq(t, "4!", s)
+	must.True(t, finished)
+}
+
+func TestFutureChainError(t *testing.T) {
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+	called := false
+	f := concurrent.FutureMap(concurrent.Async(func() (int, error) { return 0, errFailed }), func(x int) (int, error) {
+		called = true
+		return x, nil
+	})
+	_, err := f.Await(ctx)
+	must.ErrorIs(t, err, errFailed)
+	must.False(t, called)
+
+	x, err := f.Catch(func(err error) (int, error) {
+		must.ErrorIs(t, err, errFailed)
+		return -1, nil
+	}).Await(ctx)
+	must.NoError(t, err)
+	must.Eq(t, -1, x)
+
+	// a panic propagates through the chain as an error
+	panicked := concurrent.FutureThen(concurrent.Async(func() (int, error) { return 1, nil }), func(int) *concurrent.Future[int] {
+		panic("boom")
+	})
+	_, err = concurrent.FutureMap(panicked, func(x int) (int, error) { return x, nil }).Await(ctx)
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(err, &pe))
+}
+
+func TestFutureAll(t *testing.T) {
+	ctx := context.Background()
+	values, err := concurrent.FutureAll(
+		concurrent.Async(func() (int, error) {
+			time.Sleep(time.Millisecond)
+			return 1, nil
+		}),
+		concurrent.Async(func() (int, error) { return 2, nil }),
+	).Await(ctx)
+	must.NoError(t, err)
+	must.Eq(t, []int{1, 2}, values)
+
+	errFailed := errors.New("failed")
+	slow := concurrent.AsyncContext(ctx, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	_, err = concurrent.FutureAll(slow, concurrent.Async(func() (int, error) { return 0, errFailed })).Await(ctx)
+	must.ErrorIs(t, err, errFailed)
+	// the other futures are cancelled on the first failure
+	_, err = slow.Await(ctx)
+	must.ErrorIs(t, err, context.Canceled)
+}
+
+func TestFutureAny(t *testing.T) {
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+	slow := concurrent.AsyncContext(ctx, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	x, err := concurrent.FutureAny(
+		concurrent.Async(func() (int, error) { return 0, errFailed }),
+		slow,
+		concurrent.Async(func() (int, error) { return 3, nil }),
+	).Await(ctx)
+	must.NoError(t, err)
+	must.Eq(t, 3, x)
+	// the other futures are cancelled on the first success
+	_, err = slow.Await(ctx)
+	must.ErrorIs(t, err, context.Canceled)
+
+	_, err = concurrent.FutureAny(
+		concurrent.Async(func() (int, error) { return 0, errFailed }),
+		concurrent.Async(func() (int, error) { panic("boom") }),
+	).Await(ctx)
+	must.ErrorIs(t, err, errFailed)
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(err, &pe))
+
+	_, err = concurrent.FutureAny[int]().Await(ctx)
+	must.Error(t, err)
+}
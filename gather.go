@@ -0,0 +1,194 @@
+package concurrent
+
+import (
+	"context"
+	"time"
+
+	"github.com/gregwebs/errors"
+)
+
+// ScatterResult is the outcome of one of the functions of [ScatterGather].
+type ScatterResult[T any] struct {
+	Value T
+	Err   error
+}
+
+// ScatterGather runs each function in a go routine and waits until they all finish or the timeout passes.
+// results[i] is the result of fns[i].
+// A function that did not finish in time has the error [context.DeadlineExceeded],
+// or the cause of ctx if ctx was done first.
+// The functions are given a context that is cancelled when ScatterGather returns,
+// but ScatterGather does not wait for functions that did not finish.
+//
+// This is the pattern for aggregating responses from multiple backends where partial results are acceptable.
+// A panic in a function is recovered and returned as its error.
+func ScatterGather[T any](ctx context.Context, timeout time.Duration, fns ...func(context.Context) (T, error)) []ScatterResult[T] {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type indexed struct {
+		i      int
+		result ScatterResult[T]
+	}
+	// buffered so that functions finishing after the timeout do not block
+	finished := make(chan indexed, len(fns))
+	for i, fn := range fns {
+		go func() {
+			var result ScatterResult[T]
+			result.Err = Recovered(func() error {
+				var err error
+				result.Value, err = fn(ctx)
+				return err
+			})
+			finished <- indexed{i: i, result: result}
+		}()
+	}
+
+	results := make([]ScatterResult[T], len(fns))
+	received := make([]bool, len(fns))
+	for remaining := len(fns); remaining > 0; remaining-- {
+		select {
+		case f := <-finished:
+			results[f.i] = f.result
+			received[f.i] = true
+		case <-ctx.Done():
+			// select picks randomly when results finished at the same time as the timeout,
+			// so take the results that are ready before marking the others as timed out
+			for ready := true; ready; {
+				select {
+				case f := <-finished:
+					results[f.i] = f.result
+					received[f.i] = true
+				default:
+					ready = false
+				}
+			}
+			for i := range results {
+				if !received[i] {
+					results[i].Err = context.Cause(ctx)
+				}
+			}
+			return results
+		}
+	}
+	return results
+}
+
+// First runs each function in a go routine and returns the first successful result.
+// The context given to the functions is cancelled once First returns,
+// but First does not wait for the other functions to finish.
+// An error is only returned if all the functions fail, in which case all the errors are joined.
+// If ctx is done first, its cause is returned.
+// A panic in a function is recovered and treated as an error.
+func First[T any](ctx context.Context, fns ...func(context.Context) (T, error)) (T, error) {
+	var zero T
+	if len(fns) == 0 {
+		return zero, errors.New("concurrent.First: no functions given")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		value T
+		err   error
+	}
+	// buffered so that functions finishing after First returns do not block
+	finished := make(chan outcome, len(fns))
+	for _, fn := range fns {
+		go func() {
+			var o outcome
+			o.err = Recovered(func() error {
+				var err error
+				o.value, err = fn(ctx)
+				return err
+			})
+			finished <- o
+		}()
+	}
+
+	errs := make([]error, 0, len(fns))
+	for range fns {
+		select {
+		case o := <-finished:
+			if o.err == nil {
+				return o.value, nil
+			}
+			errs = append(errs, o.err)
+		case <-ctx.Done():
+			return zero, context.Cause(ctx)
+		}
+	}
+	return zero, errors.Join(errs...)
+}
+
+// gather runs each function in a go routine, cancelling the context given to the others when one fails.
+func gather(ctx context.Context, fns ...func(context.Context) error) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	errs := GoN(len(fns), func(i int) error {
+		err := Recovered(func() error { return fns[i](ctx) })
+		if err != nil {
+			cancel(err)
+		}
+		return err
+	})
+	return errors.Join(errs...)
+}
+
+// Gather2 runs two functions with different result types in parallel and waits for both.
+// When a function fails, the context given to the other function is cancelled.
+// The errors of all functions are joined.
+// The result of a function that succeeded is returned even if the other function failed.
+// A panic in a function is recovered and returned as an error.
+func Gather2[A any, B any](
+	ctx context.Context,
+	fa func(context.Context) (A, error),
+	fb func(context.Context) (B, error),
+) (A, B, error) {
+	var a A
+	var b B
+	err := gather(ctx,
+		func(ctx context.Context) (err error) { a, err = fa(ctx); return err },
+		func(ctx context.Context) (err error) { b, err = fb(ctx); return err },
+	)
+	return a, b, err
+}
+
+// Gather3 is the same as [Gather2] but for three functions.
+func Gather3[A any, B any, C any](
+	ctx context.Context,
+	fa func(context.Context) (A, error),
+	fb func(context.Context) (B, error),
+	fc func(context.Context) (C, error),
+) (A, B, C, error) {
+	var a A
+	var b B
+	var c C
+	err := gather(ctx,
+		func(ctx context.Context) (err error) { a, err = fa(ctx); return err },
+		func(ctx context.Context) (err error) { b, err = fb(ctx); return err },
+		func(ctx context.Context) (err error) { c, err = fc(ctx); return err },
+	)
+	return a, b, c, err
+}
+
+// Gather4 is the same as [Gather2] but for four functions.
+func Gather4[A any, B any, C any, D any](
+	ctx context.Context,
+	fa func(context.Context) (A, error),
+	fb func(context.Context) (B, error),
+	fc func(context.Context) (C, error),
+	fd func(context.Context) (D, error),
+) (A, B, C, D, error) {
+	var a A
+	var b B
+	var c C
+	var d D
+	err := gather(ctx,
+		func(ctx context.Context) (err error) { a, err = fa(ctx); return err },
+		func(ctx context.Context) (err error) { b, err = fb(ctx); return err },
+		func(ctx context.Context) (err error) { c, err = fc(ctx); return err },
+		func(ctx context.Context) (err error) { d, err = fd(ctx); return err },
+	)
+	return a, b, c, d, err
+}
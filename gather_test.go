@@ -0,0 +1,132 @@
+package concurrent_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestScatterGather(t *testing.T) {
+	ctx := context.Background()
+	must.Len(t, 0, concurrent.ScatterGather[int](ctx, time.Second))
+
+	errBad := errors.New("bad")
+	results := concurrent.ScatterGather(ctx, 20*time.Millisecond,
+		func(context.Context) (int, error) { return 1, nil },
+		func(context.Context) (int, error) { return 0, errBad },
+		func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			time.Sleep(10 * time.Millisecond)
+			return 3, nil
+		},
+		func(context.Context) (int, error) { panic("four") },
+	)
+	must.Len(t, 4, results)
+	must.Eq(t, 1, results[0].Value)
+	must.Nil(t, results[0].Err)
+	must.Eq(t, errBad, results[1].Err)
+	must.ErrorIs(t, results[2].Err, context.DeadlineExceeded)
+	must.Eq(t, 0, results[2].Value)
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(results[3].Err, &pe))
+
+	// returns without waiting for the timeout when everything finishes
+	start := time.Now()
+	strs := concurrent.ScatterGather(ctx, time.Minute,
+		func(context.Context) (string, error) { return "a", nil },
+		func(context.Context) (string, error) { return "b", nil },
+	)
+	must.Less(t, time.Second, time.Since(start))
+	must.Eq(t, "a", strs[0].Value)
+	must.Eq(t, "b", strs[1].Value)
+}
+
+func TestFirst(t *testing.T) {
+	ctx := context.Background()
+	_, err := concurrent.First[int](ctx)
+	must.Error(t, err)
+
+	cancelled := make(chan struct{})
+	value, err := concurrent.First(ctx,
+		func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return "slow", nil
+		},
+		func(context.Context) (string, error) { return "", errors.New("failed") },
+		func(context.Context) (string, error) {
+			time.Sleep(time.Millisecond)
+			return "fast", nil
+		},
+	)
+	must.NoError(t, err)
+	must.Eq(t, "fast", value)
+	<-cancelled
+
+	errA := errors.New("a")
+	errB := errors.New("b")
+	_, err = concurrent.First(ctx,
+		func(context.Context) (int, error) { return 0, errA },
+		func(context.Context) (int, error) { return 0, errB },
+		func(context.Context) (int, error) { panic("c") },
+	)
+	must.ErrorIs(t, err, errA)
+	must.ErrorIs(t, err, errB)
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(err, &pe))
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = concurrent.First(ctx, func(ctx context.Context) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 1, nil
+	})
+	must.ErrorIs(t, err, context.Canceled)
+}
+
+func TestGather(t *testing.T) {
+	ctx := context.Background()
+	n, s, err := concurrent.Gather2(ctx,
+		func(context.Context) (int, error) { return 1, nil },
+		func(context.Context) (string, error) { return "two", nil },
+	)
+	must.NoError(t, err)
+	must.Eq(t, 1, n)
+	must.Eq(t, "two", s)
+
+	errBad := errors.New("bad")
+	n, s, err = concurrent.Gather2(ctx,
+		func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			return 1, context.Cause(ctx)
+		},
+		func(context.Context) (string, error) { return "", errBad },
+	)
+	must.ErrorIs(t, err, errBad)
+	must.Eq(t, 1, n)
+	must.Eq(t, "", s)
+
+	_, _, _, err = concurrent.Gather3(ctx,
+		func(context.Context) (int, error) { return 1, nil },
+		func(context.Context) (bool, error) { panic("panic") },
+		func(context.Context) (float64, error) { return 3, nil },
+	)
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(err, &pe))
+
+	a, b, c, d, err := concurrent.Gather4(ctx,
+		func(context.Context) (int, error) { return 1, nil },
+		func(context.Context) (bool, error) { return true, nil },
+		func(context.Context) (float64, error) { return 3, nil },
+		func(context.Context) ([]string, error) { return []string{"4"}, nil },
+	)
+	must.NoError(t, err)
+	must.Eq(t, 1, a)
+	must.True(t, b)
+	must.Eq(t, 3.0, c)
+	must.Eq(t, []string{"4"}, d)
+}
@@ -0,0 +1,268 @@
+package concurrent
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"runtime/metrics"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
+
+// Use returns a GoRoutine that wraps each launched function with the middleware mw.
+// The function returned by mw is what runs in the launched go routine.
+// Use can be called multiple times: the middleware of the first call is the outermost.
+//
+//	gr := GoConcurrent().Use(logging).Use(metrics)
+//
+// next returns the error of the launched function, so middleware can log or count failures.
+// The work given to mw by [GoRoutine.GoN] and [Group] already recovers panics and records errors,
+// so the error returned by mw is only seen by the middleware wrapping it: it should normally return the error of next.
+func (gr GoRoutine) Use(mw func(next func() error) func() error) GoRoutine {
+	return GoRoutine(func(work func() error) { gr(mw(work)) })
+}
+
+// OnStart returns a GoRoutine that calls hook in each launched go routine before the work starts.
+func (gr GoRoutine) OnStart(hook func()) GoRoutine {
+	return gr.Use(func(next func() error) func() error {
+		return func() error {
+			hook()
+			return next()
+		}
+	})
+}
+
+// OnFinish returns a GoRoutine that calls hook in each launched go routine after the work finishes
+// with the error of the work, which is nil on success, and the duration of the work.
+// A panic in the work is given to the hook as a [*PanicError].
+func (gr GoRoutine) OnFinish(hook func(err error, d time.Duration)) GoRoutine {
+	return gr.Use(func(next func() error) func() error {
+		return func() error {
+			start := time.Now()
+			err := next()
+			hook(err, time.Since(start))
+			return err
+		}
+	})
+}
+
+// WithLabels returns a GoRoutine that sets the given pprof labels on each launched go routine,
+// so that profiles can attribute the work.
+// labels are key/value pairs as given to [pprof.Labels], which panics on an odd number of strings.
+func (gr GoRoutine) WithLabels(labels ...string) GoRoutine {
+	labelSet := pprof.Labels(labels...)
+	return gr.Use(func(next func() error) func() error {
+		return func() (err error) {
+			pprof.Do(context.Background(), labelSet, func(context.Context) { err = next() })
+			return err
+		}
+	})
+}
+
+// WithContext returns a GoRoutine that sets the pprof labels of ctx on each launched go routine
+// while it runs the work, so it combines with a [Pool] or other launchers that reuse go routines.
+// Use it with the context of a request so that profiles attribute the work it launches to the request.
+// Labels are added to a context with [pprof.WithLabels] or [pprof.Do],
+// so a context derived with more labels can be given to attribute the work more precisely than the request.
+func (gr GoRoutine) WithContext(ctx context.Context) GoRoutine {
+	return gr.Use(func(next func() error) func() error {
+		return func() (err error) {
+			pprof.Do(ctx, pprof.Labels(), func(context.Context) { err = next() })
+			return err
+		}
+	})
+}
+
+// GoWithContext is a shorthand for GoConcurrent().WithContext(ctx). See [GoRoutine.WithContext].
+func GoWithContext(ctx context.Context) GoRoutine {
+	return GoConcurrent().WithContext(ctx)
+}
+
+// GoRateLimited returns a GoRoutine that launches at most perSecond go routines per second,
+// allowing a burst of up to burst launches at once.
+// Launching blocks until the rate allows it.
+// This makes [GoRoutine.GoN], [GoEachRoutine], and [Group] (via [Group.SetGoRoutine]) rate limited.
+//
+// Launches are never dropped because GoN and Group wait for every launched function to finish.
+// perSecond must be positive.
+func GoRateLimited(perSecond float64, burst int) GoRoutine {
+	limiter := newRateLimiter(perSecond, burst)
+	return GoRoutine(func(work func() error) {
+		limiter.wait()
+		go work()
+	})
+}
+
+// rateLimiter tracks the time at which the next launch would be allowed without a burst.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	if perSecond <= 0 {
+		panic("concurrent: rate must be positive")
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	interval := time.Duration(float64(time.Second) / perSecond)
+	return &rateLimiter{
+		interval: interval,
+		burst:    time.Duration(burst-1) * interval,
+	}
+}
+
+// wait blocks until the caller is allowed to proceed.
+func (rl *rateLimiter) wait() {
+	if delay := rl.reserve(); delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// reserve takes the next slot and returns how long the caller must wait before proceeding.
+func (rl *rateLimiter) reserve() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := time.Now()
+	if rl.next.Before(now) {
+		rl.next = now
+	}
+	delay := rl.next.Sub(now) - rl.burst
+	rl.next = rl.next.Add(rl.interval)
+	return delay
+}
+
+// GoWeighted allows limiting go routines by a total cost rather than by a count.
+// It returns a constructor of GoRoutines that share the given capacity.
+// Each go routine launched by the GoRoutine for a cost takes that many slots of the capacity until it finishes.
+// Launching blocks until enough capacity is available.
+//
+//	weighted := GoWeighted(8)
+//	heavy, light := weighted(4), weighted(1)
+//
+// The cost must not be negative or larger than the capacity, which would block launching forever.
+func GoWeighted(capacity int64) func(cost int64) GoRoutine {
+	sem := NewSemaphore(capacity)
+	return func(cost int64) GoRoutine {
+		if cost < 0 {
+			panic(fmt.Errorf("concurrent: cost %d is negative", cost))
+		}
+		if cost > capacity {
+			panic(fmt.Errorf("concurrent: cost %d is larger than the capacity %d", cost, capacity))
+		}
+		return GoRoutine(func(work func() error) {
+			// Acquire only fails when ctx is done
+			_ = sem.Acquire(context.Background(), cost)
+			go func() {
+				defer sem.Release(cost)
+				_ = work()
+			}()
+		})
+	}
+}
+
+// GoSerialShuffled is like [GoSerial] in that only one function runs at a time,
+// but the launched functions run in a random order.
+// This helps expose ordering bugs that [GoConcurrent] only exposes intermittently.
+//
+// Launched functions are queued and run by a single go routine that picks a random function from the queue.
+func GoSerialShuffled() GoRoutine {
+	var mu sync.Mutex
+	var pending []func() error
+	running := false
+	run := func() {
+		for {
+			// give the launcher a chance to queue more work
+			runtime.Gosched()
+			mu.Lock()
+			if len(pending) == 0 {
+				running = false
+				mu.Unlock()
+				return
+			}
+			i := rand.IntN(len(pending))
+			work := pending[i]
+			pending[i] = pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			mu.Unlock()
+			_ = work()
+		}
+	}
+	return GoRoutine(func(work func() error) {
+		mu.Lock()
+		defer mu.Unlock()
+		pending = append(pending, work)
+		if !running {
+			running = true
+			go run()
+		}
+	})
+}
+
+// SetPanicHandler returns a GoRoutine that gives the panics recovered in its launched go routines to handler,
+// for example to send them to a crash reporter.
+// This applies to [GoRoutine.GoN], [GoEachRoutine], [Pool], [Group], and everything else that launches with the GoRoutine.
+// The handler is given the recovered panic value and returns the error to use in place of the [*PanicError].
+// If the handler returns nil, the panic is not reported as an error.
+// A panic in the handler is converted to a *PanicError.
+// If SetPanicHandler is called multiple times, the handler of the last call is used.
+func (gr GoRoutine) SetPanicHandler(handler func(recovered any) error) GoRoutine {
+	return gr.withPanicOptions(func(opts *panicOptions) { opts.handler = handler })
+}
+
+// SetPanicStack returns a GoRoutine that keeps at most depth frames of the stack trace
+// in the [*PanicError] of a panic in its launched go routines, starting at the function that panicked.
+// A depth of 0 skips capturing the stack trace, which avoids its cost for panics that are expected.
+// A negative depth keeps the full stack trace, which is the default.
+// The panic value is always kept and available from [PanicError.Value].
+func (gr GoRoutine) SetPanicStack(depth int) GoRoutine {
+	return gr.withPanicOptions(func(opts *panicOptions) {
+		opts.stackLimited = depth >= 0
+		opts.stackDepth = depth
+	})
+}
+
+// LockOSThread returns a GoRoutine whose launched go routines run locked to an OS thread
+// with [runtime.LockOSThread]. This is required by some cgo, graphics, and syscall code.
+func (gr GoRoutine) LockOSThread() GoRoutine {
+	return gr.Use(func(next func() error) func() error {
+		return func() error {
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+			return next()
+		}
+	})
+}
+
+// WithAdmission returns a GoRoutine that only launches when admit returns true.
+// While admit returns false, launching blocks and admit is checked again with an increasing delay of up to 100ms.
+// See [HeapBelow] for pausing launches under memory pressure.
+func (gr GoRoutine) WithAdmission(admit func() bool) GoRoutine {
+	return GoRoutine(func(work func() error) {
+		delay := time.Millisecond
+		for !admit() {
+			time.Sleep(delay)
+			delay = min(2*delay, 100*time.Millisecond)
+		}
+		gr(work)
+	})
+}
+
+// HeapBelow returns an admission check for [GoRoutine.WithAdmission]
+// that admits while the memory used by live and not yet collected heap objects is below maxBytes.
+// This protects a batch job from running out of memory because of an unbounded fan-out.
+func HeapBelow(maxBytes uint64) func() bool {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	var mu sync.Mutex
+	return func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		metrics.Read(sample)
+		return sample[0].Value.Uint64() < maxBytes
+	}
+}
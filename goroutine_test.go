@@ -0,0 +1,348 @@
+package concurrent_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestGoRoutineUse(t *testing.T) {
+	var calls []string
+	trace := func(name string) func(func() error) func() error {
+		return func(next func() error) func() error {
+			return func() error {
+				calls = append(calls, name+" start")
+				err := next()
+				if err != nil {
+					calls = append(calls, name+" error")
+				}
+				calls = append(calls, name+" end")
+				return err
+			}
+		}
+	}
+	gr := concurrent.GoSerial().Use(trace("outer")).Use(trace("inner"))
+	err := gr.GoN(1, func(_ int) error {
+		calls = append(calls, "work")
+		return nil
+	})
+	must.Nil(t, err)
+	must.Eq(t, []string{"outer start", "inner start", "work", "inner end", "outer end"}, calls)
+
+	calls = nil
+	group, _ := concurrent.NewGroupContext(context.Background())
+	group.SetGoRoutine(gr)
+	group.Go(func() error { panic("recovered inside") })
+	must.Len(t, 1, group.Wait())
+	// the middleware sees the panic as an error
+	must.Eq(t, []string{"outer start", "inner start", "inner error", "inner end", "outer error", "outer end"}, calls)
+
+	calls = nil
+	errFailed := errors.New("failed")
+	err = gr.GoN(1, func(_ int) error { return errFailed })
+	must.Len(t, 1, err)
+	must.ErrorIs(t, err[0], errFailed)
+	must.Eq(t, []string{"outer start", "inner start", "inner error", "inner end", "outer error", "outer end"}, calls)
+}
+
+func TestGoRoutineHooks(t *testing.T) {
+	var started, finished, failed int32
+	var total int64
+	gr := concurrent.GoConcurrent().
+		OnStart(func() { atomic.AddInt32(&started, 1) }).
+		OnFinish(func(err error, d time.Duration) {
+			atomic.AddInt32(&finished, 1)
+			atomic.AddInt64(&total, int64(d))
+			if err != nil {
+				atomic.AddInt32(&failed, 1)
+			}
+		})
+	err := gr.GoN(3, func(_ int) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	must.Nil(t, err)
+	must.Eq(t, 3, atomic.LoadInt32(&started))
+	must.Eq(t, 3, atomic.LoadInt32(&finished))
+	must.GreaterEq(t, int64(3*time.Millisecond), atomic.LoadInt64(&total))
+
+	// hooks run for work that panics
+	err = gr.GoN(1, func(_ int) error { panic("boom") })
+	must.Len(t, 1, err)
+	must.Eq(t, 4, atomic.LoadInt32(&finished))
+	must.Eq(t, 1, atomic.LoadInt32(&failed))
+}
+
+func TestGoRoutineWithLabels(t *testing.T) {
+	gr := concurrent.GoConcurrent().WithLabels("worker", "labeled")
+	profiled := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- errors.Join(gr.GoN(1, func(_ int) error {
+			<-profiled
+			return nil
+		})...)
+	}()
+
+	var profile bytes.Buffer
+	for !bytes.Contains(profile.Bytes(), []byte(`"worker":"labeled"`)) {
+		profile.Reset()
+		must.NoError(t, pprof.Lookup("goroutine").WriteTo(&profile, 1))
+	}
+	close(profiled)
+	must.NoError(t, <-done)
+}
+
+func TestGoWithContext(t *testing.T) {
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("request", "inherited"))
+	gr := concurrent.GoWithContext(ctx)
+	profiled := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- errors.Join(gr.GoN(1, func(_ int) error {
+			<-profiled
+			return nil
+		})...)
+	}()
+
+	var profile bytes.Buffer
+	for !bytes.Contains(profile.Bytes(), []byte(`"request":"inherited"`)) {
+		profile.Reset()
+		must.NoError(t, pprof.Lookup("goroutine").WriteTo(&profile, 1))
+	}
+	close(profiled)
+	must.NoError(t, <-done)
+
+	// a derived context adds labels and combines with a Pool
+	pool := concurrent.NewPool(1)
+	defer pool.Close()
+	derived := pprof.WithLabels(ctx, pprof.Labels("step", "derived"))
+	gr = pool.GoRoutine().WithContext(derived)
+	profiled = make(chan struct{})
+	go func() {
+		done <- errors.Join(gr.GoN(1, func(_ int) error {
+			<-profiled
+			return nil
+		})...)
+	}()
+	profile.Reset()
+	for !bytes.Contains(profile.Bytes(), []byte(`"request":"inherited", "step":"derived"`)) {
+		profile.Reset()
+		must.NoError(t, pprof.Lookup("goroutine").WriteTo(&profile, 1))
+	}
+	close(profiled)
+	must.NoError(t, <-done)
+}
+
+func TestGoRateLimited(t *testing.T) {
+	gr := concurrent.GoRateLimited(100, 2)
+	start := time.Now()
+	var launched int32
+	err := gr.GoN(5, func(_ int) error {
+		atomic.AddInt32(&launched, 1)
+		return nil
+	})
+	must.Nil(t, err)
+	must.Eq(t, 5, atomic.LoadInt32(&launched))
+	// 2 launches are a burst, the other 3 wait 10ms each
+	must.GreaterEq(t, 30*time.Millisecond, time.Since(start))
+
+	group, _ := concurrent.NewGroupContext(context.Background())
+	group.SetGoRoutine(concurrent.GoRateLimited(1000, 1))
+	for i := 0; i < 3; i++ {
+		group.Go(func() error { return nil })
+	}
+	must.Nil(t, group.Wait())
+}
+
+func TestGoWeighted(t *testing.T) {
+	const capacity = 4
+	weighted := concurrent.GoWeighted(capacity)
+	var used int64
+	var maxUsed int64
+	work := func(cost int64) func(int) error {
+		return func(_ int) error {
+			n := atomic.AddInt64(&used, cost)
+			for {
+				m := atomic.LoadInt64(&maxUsed)
+				if n <= m || atomic.CompareAndSwapInt64(&maxUsed, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&used, -cost)
+			return nil
+		}
+	}
+	heavy, light := weighted(3), weighted(1)
+	done := make(chan []error)
+	go func() { done <- heavy.GoN(10, work(3)) }()
+	must.Nil(t, light.GoN(20, work(1)))
+	must.Nil(t, <-done)
+	must.LessEq(t, capacity, atomic.LoadInt64(&maxUsed))
+	must.Positive(t, atomic.LoadInt64(&maxUsed))
+
+	// a negative cost would add capacity and a cost above the capacity would never launch
+	for _, cost := range []int64{-1, capacity + 1} {
+		err := concurrent.Recovered(func() error {
+			weighted(cost)
+			return nil
+		})
+		must.Error(t, err)
+	}
+}
+
+func TestGoSerialShuffled(t *testing.T) {
+	gr := concurrent.GoSerialShuffled()
+	shuffled := false
+	for attempt := 0; attempt < 10 && !shuffled; attempt++ {
+		var order []int
+		var active int32
+		err := gr.GoN(20, func(i int) error {
+			must.Eq(t, 1, atomic.AddInt32(&active, 1))
+			order = append(order, i)
+			atomic.AddInt32(&active, -1)
+			return nil
+		})
+		must.Nil(t, err)
+		must.Len(t, 20, order)
+		for i := range order {
+			if order[i] != i {
+				shuffled = true
+			}
+		}
+	}
+	must.True(t, shuffled)
+}
+
+func TestGoRoutineWithAdmission(t *testing.T) {
+	var admitted atomic.Bool
+	var checks int32
+	gr := concurrent.GoConcurrent().WithAdmission(func() bool {
+		atomic.AddInt32(&checks, 1)
+		return admitted.Load()
+	})
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		admitted.Store(true)
+	}()
+	var launched int32
+	err := gr.GoN(3, func(_ int) error {
+		must.True(t, admitted.Load())
+		atomic.AddInt32(&launched, 1)
+		return nil
+	})
+	must.Nil(t, err)
+	must.Eq(t, 3, atomic.LoadInt32(&launched))
+	must.Greater(t, 3, atomic.LoadInt32(&checks))
+
+	must.True(t, concurrent.HeapBelow(math.MaxUint64)())
+	must.False(t, concurrent.HeapBelow(1)())
+}
+
+func TestGoRoutineLockOSThread(t *testing.T) {
+	errBad := errors.New("bad")
+	err := concurrent.GoConcurrent().LockOSThread().GoN(3, func(i int) error {
+		if i == 1 {
+			return errBad
+		}
+		return nil
+	})
+	must.Eq(t, []error{errBad}, err)
+}
+
+func TestGoRoutineSetPanicHandler(t *testing.T) {
+	errReported := errors.New("reported")
+	var reported sync.Map
+	handler := func(recovered any) error {
+		reported.Store(recovered, true)
+		if recovered == 0 {
+			return nil
+		}
+		return errReported
+	}
+	fn := func(i int) error {
+		if i < 2 {
+			panic(i)
+		}
+		return nil
+	}
+
+	errs := concurrent.GoConcurrent().SetPanicHandler(handler).GoN(3, fn)
+	must.Eq(t, []error{errReported}, errs)
+	_, ok := reported.Load(1)
+	must.True(t, ok)
+
+	pool := concurrent.NewPool(2)
+	defer pool.Close()
+	errs = pool.GoRoutine().SetPanicHandler(handler).GoN(3, fn)
+	must.Eq(t, []error{errReported}, errs)
+
+	// the handler of the last call is used
+	errLast := errors.New("last")
+	errs = concurrent.GoConcurrent().SetPanicHandler(handler).
+		Use(func(next func() error) func() error { return next }).
+		SetPanicHandler(func(recovered any) error { return errLast }).
+		GoN(1, fn)
+	must.Eq(t, []error{errLast}, errs)
+
+	// go routines launched without the handler are not affected
+	errs = concurrent.GoConcurrent().GoN(1, fn)
+	must.Len(t, 1, errs)
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(errs[0], &pe))
+
+	// a panic in the handler is converted to an error
+	errs = concurrent.GoSerial().SetPanicHandler(func(recovered any) error { panic("handler") }).GoN(1, fn)
+	must.Len(t, 1, errs)
+	must.True(t, errors.As(errs[0], &pe))
+	must.Eq[any](t, "handler", pe.Value())
+}
+
+func TestGoRoutineSetPanicStack(t *testing.T) {
+	fn := func(int) error { panicInHelper(); return nil }
+	var pe *concurrent.PanicError
+
+	errs := concurrent.GoConcurrent().SetPanicStack(0).GoN(1, fn)
+	must.True(t, errors.As(errs[0], &pe))
+	must.Eq[any](t, "helper", pe.Value())
+	must.Nil(t, pe.Stack())
+	must.EqError(t, errs[0], fmt.Sprintf("%+v", errs[0]))
+
+	errs = concurrent.GoConcurrent().SetPanicStack(1).GoN(1, fn)
+	must.True(t, errors.As(errs[0], &pe))
+	stack := string(pe.Stack())
+	must.StrHasPrefix(t, "goroutine ", stack)
+	must.StrContains(t, stack, "panicInHelper")
+	must.StrNotContains(t, stack, "TestGoRoutineSetPanicStack")
+	must.StrHasSuffix(t, "...additional frames elided...\n", stack)
+
+	errs = concurrent.GoConcurrent().SetPanicStack(2).GoN(1, fn)
+	must.True(t, errors.As(errs[0], &pe))
+	must.StrContains(t, string(pe.Stack()), "TestGoRoutineSetPanicStack")
+
+	// the default and a negative depth keep the full stack trace
+	errs = concurrent.GoConcurrent().SetPanicStack(0).SetPanicStack(-1).GoN(1, fn)
+	must.True(t, errors.As(errs[0], &pe))
+	must.StrContains(t, string(pe.Stack()), "runtime/debug.Stack")
+
+	// the stack options combine with a panic handler
+	errs = concurrent.GoConcurrent().SetPanicStack(0).
+		SetPanicHandler(func(recovered any) error { panic(recovered) }).GoN(1, fn)
+	must.True(t, errors.As(errs[0], &pe))
+	must.Nil(t, pe.Stack())
+}
+
+func panicInHelper() {
+	panic("helper")
+}
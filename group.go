@@ -42,7 +42,6 @@ import (
 	"sync"
 
 	"github.com/gregwebs/errors"
-	"github.com/gregwebs/go-recovery"
 )
 
 type token struct{}
@@ -51,26 +50,33 @@ type token struct{}
 // Improvements:
 //   - Wait() will return a slice of all errors encountered.
 //   - panics in the functions that are ran are recovered and converted to errors.
+//     The error is a [*PanicError] as described in [GoN].
 //   - Go routine launching can be configured with [*Group.SetGoRoutine]
 //
 // Must be constructed with [NewGroupContext]
 type Group struct {
-	errChan   UnboundedChan[error]
-	wg        sync.WaitGroup
-	cancel    func(error)
-	sem       chan token
-	goRoutine GoRoutine
+	errs         *MPSCQueue[error]
+	wg           sync.WaitGroup
+	cancel       func(error)
+	sem          chan token
+	goRoutine    GoRoutine
+	panicHandler func(recovered any) error
 }
 
 func (g *Group) do(fn func() error) {
 	g.wg.Add(1)
-	go recovery.GoHandler(func(err error) { g.errChan.Send(err) }, func() error {
+	gr := g.goRoutine
+	if g.panicHandler != nil {
+		gr = gr.SetPanicHandler(g.panicHandler)
+	}
+	gr(func() error {
 		defer g.done()
-		if err := fn(); err != nil {
-			g.errChan.Send(err)
+		err := Recovered(fn)
+		if err != nil {
+			g.errs.Push(err)
 			g.cancel(err)
 		}
-		return nil
+		return err
 	})
 }
 
@@ -84,11 +90,10 @@ func (g *Group) done() {
 // Wait waits for any outstanding go routines and returns their errors
 // If go routines are started during this Wait,
 // their errors might not show up until the next Wait
+// Wait must not be called from multiple go routines at once.
 func (g *Group) Wait() []error {
 	g.wg.Wait()
-	prevErrChan := g.errChan
-	g.errChan = NewUnboundedChan[error]()
-	errs := prevErrChan.Drain()
+	errs := g.errs.PopAll()
 	if g.cancel != nil {
 		g.cancel(errors.Join(errs...))
 	}
@@ -101,7 +106,7 @@ func NewGroupContext(ctx context.Context) (*Group, context.Context) {
 	ctx, cancel := context.WithCancelCause(ctx)
 	return &Group{
 		cancel:    cancel,
-		errChan:   NewUnboundedChan[error](),
+		errs:      NewMPSCQueue[error](),
 		goRoutine: GoConcurrent(),
 	}, ctx
 }
@@ -111,6 +116,16 @@ func (g *Group) SetGoRoutine(gr GoRoutine) {
 	g.goRoutine = gr
 }
 
+// SetPanicHandler allows routing panics to a crash reporter.
+// It applies [GoRoutine.SetPanicHandler] to the GoRoutine of the group, including one set later with [Group.SetGoRoutine].
+// The handler is given the recovered panic value and returns the error to use in place of the panic.
+// If the handler returns nil, the panic is not reported as an error.
+// A panic in the handler is converted to an error.
+// Without a handler, a panic is converted to a [*PanicError].
+func (g *Group) SetPanicHandler(handler func(recovered any) error) {
+	g.panicHandler = handler
+}
+
 func (g *Group) Go(fn func() error) {
 	if g.sem != nil {
 		g.sem <- token{}
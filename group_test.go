@@ -40,6 +40,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -64,20 +65,21 @@ func TestZeroGroup(t *testing.T) {
 	for _, tc := range cases {
 		g, _ := concurrent.NewGroupContext(context.Background())
 
-		var firstErr error
 		for i, err := range tc.errs {
 			err := err
 			g.Go(func() error { return err })
 
-			if firstErr == nil && err != nil {
-				firstErr = err
+			// Wait returns the errors that occurred since the previous Wait
+			var want []error
+			if err != nil {
+				want = []error{err}
 			}
 
 			gErr := g.Wait()
-			if len(gErr) > 0 && gErr[0] != firstErr {
+			if !slices.Equal(gErr, want) {
 				t.Errorf("after %T.Go(func() error { return err }) for err in %v\n"+
 					"g.Wait() = %v; want %v",
-					g, tc.errs[:i+1], err, firstErr)
+					g, tc.errs[:i+1], gErr, want)
 			}
 		}
 	}
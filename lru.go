@@ -0,0 +1,137 @@
+package concurrent
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a cache that is safe to use from multiple go routines.
+// Once it holds its capacity, setting a new key evicts the least recently used key.
+// Optionally, keys can expire with [*LRU.SetTTL].
+//
+// Must be constructed with [NewLRU]
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	onEvict  func(K, V)
+	// order has the most recently used entry at the front
+	order   *list.List
+	entries map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// NewLRU creates an LRU that holds at most capacity keys.
+// capacity must be positive.
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
+	if capacity < 1 {
+		panic("concurrent: capacity must be positive")
+	}
+	return &LRU[K, V]{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[K]*list.Element),
+	}
+}
+
+// SetTTL makes keys that are set from now on expire after ttl.
+// A ttl of 0, the default, means keys do not expire.
+func (c *LRU[K, V]) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+// SetOnEvict sets a function that is called with each key and value that is evicted or has expired.
+// It is not called for keys that are deleted or overwritten.
+// It is called without holding the lock of the LRU, so it may use the LRU.
+func (c *LRU[K, V]) SetOnEvict(onEvict func(K, V)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEvict = onEvict
+}
+
+// Get returns the value for k and marks it as the most recently used.
+// It returns false if there is no value for k or it has expired.
+func (c *LRU[K, V]) Get(k K) (V, bool) {
+	c.mu.Lock()
+	elem, ok := c.entries[k]
+	if !ok {
+		c.mu.Unlock()
+		var zero V
+		return zero, false
+	}
+	entry := elem.Value.(*lruEntry[K, V])
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(elem)
+		onEvict := c.onEvict
+		c.mu.Unlock()
+		if onEvict != nil {
+			onEvict(entry.key, entry.value)
+		}
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	v := entry.value
+	c.mu.Unlock()
+	return v, true
+}
+
+// Set stores v for k as the most recently used key, evicting the least recently used key if the LRU is full.
+func (c *LRU[K, V]) Set(k K, v V) {
+	c.mu.Lock()
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = time.Now().Add(c.ttl)
+	}
+	if elem, ok := c.entries[k]; ok {
+		entry := elem.Value.(*lruEntry[K, V])
+		entry.value = v
+		entry.expires = expires
+		c.order.MoveToFront(elem)
+		c.mu.Unlock()
+		return
+	}
+	c.entries[k] = c.order.PushFront(&lruEntry[K, V]{key: k, value: v, expires: expires})
+	if c.order.Len() <= c.capacity {
+		c.mu.Unlock()
+		return
+	}
+	evicted := c.order.Back().Value.(*lruEntry[K, V])
+	c.remove(c.order.Back())
+	onEvict := c.onEvict
+	c.mu.Unlock()
+	if onEvict != nil {
+		onEvict(evicted.key, evicted.value)
+	}
+}
+
+// Delete removes the value for k.
+func (c *LRU[K, V]) Delete(k K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[k]; ok {
+		c.remove(elem)
+	}
+}
+
+// Len returns the number of keys in the LRU.
+// Expired keys are counted until a Get finds them expired or they are evicted.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove must be called with the lock held.
+func (c *LRU[K, V]) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry[K, V]).key)
+}
@@ -0,0 +1,68 @@
+package concurrent_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestLRU(t *testing.T) {
+	var evicted []string
+	c := concurrent.NewLRU[string, int](2)
+	c.SetOnEvict(func(k string, v int) {
+		evicted = append(evicted, k)
+		must.Eq(t, 2, c.Len())
+	})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	v, ok := c.Get("a")
+	must.True(t, ok)
+	must.Eq(t, 1, v)
+
+	c.Set("c", 3)
+	must.Eq(t, []string{"b"}, evicted)
+	_, ok = c.Get("b")
+	must.False(t, ok)
+	must.Eq(t, 2, c.Len())
+
+	c.Set("a", 10)
+	c.Set("d", 4)
+	must.Eq(t, []string{"b", "c"}, evicted)
+	v, ok = c.Get("a")
+	must.True(t, ok)
+	must.Eq(t, 10, v)
+
+	c.Delete("a")
+	must.Eq(t, 1, c.Len())
+	must.Eq(t, []string{"b", "c"}, evicted)
+}
+
+func TestLRUTTL(t *testing.T) {
+	var evicted []string
+	c := concurrent.NewLRU[string, int](2)
+	c.SetOnEvict(func(k string, v int) {
+		evicted = append(evicted, k)
+	})
+	c.SetTTL(time.Millisecond)
+	c.Set("a", 1)
+	_, ok := c.Get("a")
+	must.True(t, ok)
+	time.Sleep(2 * time.Millisecond)
+	_, ok = c.Get("a")
+	must.False(t, ok)
+	must.Eq(t, []string{"a"}, evicted)
+	must.Eq(t, 0, c.Len())
+}
+
+func TestLRUConcurrent(t *testing.T) {
+	c := concurrent.NewLRU[int, int](10)
+	errs := concurrent.GoN(100, func(i int) error {
+		c.Set(i%20, i)
+		c.Get(i % 7)
+		return nil
+	})
+	must.Nil(t, errs)
+	must.Eq(t, 10, c.Len())
+}
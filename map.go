@@ -0,0 +1,108 @@
+package concurrent
+
+import (
+	"maps"
+	"sync"
+)
+
+// Map is a map that is safe to use from multiple go routines.
+// Unlike sync.Map it is typed and supports atomic updates with Compute.
+// Functions given to its methods must not call methods of the same Map.
+//
+// The zero value is an empty Map ready to use.
+// A Map must not be copied after first use.
+type Map[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  map[K]V
+}
+
+// NewMap creates a Map containing a copy of m.
+func NewMap[K comparable, V any](m map[K]V) *Map[K, V] {
+	return &Map[K, V]{m: maps.Clone(m)}
+}
+
+// Get returns the value stored for k.
+// It returns false if there is no value for k.
+func (m *Map[K, V]) Get(k K) (V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.m[k]
+	return v, ok
+}
+
+// Set stores v for k.
+func (m *Map[K, V]) Set(k K, v V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(k, v)
+}
+
+// set must be called with the lock held.
+func (m *Map[K, V]) set(k K, v V) {
+	if m.m == nil {
+		m.m = make(map[K]V)
+	}
+	m.m[k] = v
+}
+
+// Delete removes the value for k.
+func (m *Map[K, V]) Delete(k K) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.m, k)
+}
+
+// GetOrSet returns the value stored for k if there is one.
+// Otherwise it stores v and returns it.
+// loaded is true if the value was already stored.
+func (m *Map[K, V]) GetOrSet(k K, v V) (actual V, loaded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.m[k]; ok {
+		return old, true
+	}
+	m.set(k, v)
+	return v, false
+}
+
+// Compute atomically updates the value for k.
+// fn is given the current value and whether there is one.
+// If fn returns true, its value is stored, otherwise the value for k is deleted.
+// Compute returns what fn returned.
+func (m *Map[K, V]) Compute(k K, fn func(old V, ok bool) (V, bool)) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.m[k]
+	v, keep := fn(old, ok)
+	if keep {
+		m.set(k, v)
+	} else {
+		delete(m.m, k)
+	}
+	return v, keep
+}
+
+// Len returns the number of values in the Map.
+func (m *Map[K, V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.m)
+}
+
+// Snapshot returns a copy of the map.
+func (m *Map[K, V]) Snapshot() map[K]V {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return maps.Clone(m.m)
+}
+
+// Range calls fn with each key and value in no particular order until fn returns false.
+// It iterates over a Snapshot, so fn may modify the Map
+// but will not see the modifications.
+func (m *Map[K, V]) Range(fn func(k K, v V) bool) {
+	for k, v := range m.Snapshot() {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package concurrent_test
+
+import (
+	"testing"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestMap(t *testing.T) {
+	var m concurrent.Map[string, int]
+	_, ok := m.Get("a")
+	must.False(t, ok)
+	m.Delete("a")
+	m.Set("a", 1)
+	v, ok := m.Get("a")
+	must.True(t, ok)
+	must.Eq(t, 1, v)
+
+	v, loaded := m.GetOrSet("a", 2)
+	must.True(t, loaded)
+	must.Eq(t, 1, v)
+	v, loaded = m.GetOrSet("b", 2)
+	must.False(t, loaded)
+	must.Eq(t, 2, v)
+	must.Eq(t, 2, m.Len())
+
+	m.Delete("a")
+	must.Eq(t, map[string]int{"b": 2}, m.Snapshot())
+}
+
+func TestMapCompute(t *testing.T) {
+	m := concurrent.NewMap[string, int](nil)
+	errs := concurrent.GoN(100, func(int) error {
+		m.Compute("count", func(old int, ok bool) (int, bool) {
+			return old + 1, true
+		})
+		return nil
+	})
+	must.Nil(t, errs)
+	v, _ := m.Get("count")
+	must.Eq(t, 100, v)
+
+	_, keep := m.Compute("count", func(old int, ok bool) (int, bool) {
+		must.True(t, ok)
+		return 0, false
+	})
+	must.False(t, keep)
+	must.Eq(t, 0, m.Len())
+}
+
+func TestMapRange(t *testing.T) {
+	m := concurrent.NewMap(map[int]int{1: 1, 2: 2, 3: 3})
+	sum := 0
+	m.Range(func(k int, v int) bool {
+		m.Delete(k)
+		sum += v
+		return true
+	})
+	must.Eq(t, 6, sum)
+	must.Eq(t, 0, m.Len())
+
+	m.Set(1, 1)
+	m.Set(2, 2)
+	calls := 0
+	m.Range(func(int, int) bool {
+		calls++
+		return false
+	})
+	must.Eq(t, 1, calls)
+}
@@ -0,0 +1,170 @@
+package concurrent
+
+import (
+	"slices"
+	"sync/atomic"
+)
+
+// Counter is a count that only goes up and is safe to use from multiple go routines.
+// The zero value is a Counter at 0.
+type Counter struct {
+	v atomic.Int64
+}
+
+// Inc adds 1 to the Counter.
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+// Add adds n to the Counter.
+// n should not be negative.
+func (c *Counter) Add(n int64) {
+	c.v.Add(n)
+}
+
+// Value returns the current count.
+func (c *Counter) Value() int64 {
+	return c.v.Load()
+}
+
+// Gauge is a value that goes up and down and is safe to use from multiple go routines.
+// The zero value is a Gauge at 0.
+type Gauge struct {
+	v atomic.Int64
+}
+
+// Set sets the Gauge to v.
+func (g *Gauge) Set(v int64) {
+	g.v.Store(v)
+}
+
+// Add adds n to the Gauge, n may be negative.
+func (g *Gauge) Add(n int64) {
+	g.v.Add(n)
+}
+
+// Value returns the current value.
+func (g *Gauge) Value() int64 {
+	return g.v.Load()
+}
+
+// Histogram counts observed values into buckets and is safe to use from multiple go routines.
+//
+// Must be constructed with [NewHistogram]
+type Histogram struct {
+	bounds []int64
+	// counts has a bucket for each bound and one more for the values above the last bound
+	counts []atomic.Int64
+	count  atomic.Int64
+	sum    atomic.Int64
+}
+
+// HistogramSnapshot is the state of a [Histogram] returned by Snapshot.
+type HistogramSnapshot struct {
+	// Bounds are the inclusive upper bounds of the buckets.
+	Bounds []int64
+	// Counts[i] is the number of values observed in bucket i.
+	// It has one more bucket than Bounds for the values above the last bound.
+	Counts []int64
+	Count  int64
+	Sum    int64
+}
+
+// NewHistogram creates a Histogram with buckets that have the given inclusive upper bounds.
+// The bounds are sorted. For example the bounds of a histogram of durations could be
+// int64(time.Millisecond), int64(10*time.Millisecond), int64(100*time.Millisecond).
+func NewHistogram(bounds ...int64) *Histogram {
+	bounds = slices.Clone(bounds)
+	slices.Sort(bounds)
+	return &Histogram{bounds: bounds, counts: make([]atomic.Int64, len(bounds)+1)}
+}
+
+// Observe records a value.
+func (h *Histogram) Observe(v int64) {
+	i, _ := slices.BinarySearch(h.bounds, v)
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	h.sum.Add(v)
+}
+
+// Snapshot returns the current counts.
+// Observations that happen during Snapshot might only be partially included.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	counts := make([]int64, len(h.counts))
+	for i := range h.counts {
+		counts[i] = h.counts[i].Load()
+	}
+	return HistogramSnapshot{
+		Bounds: slices.Clone(h.bounds),
+		Counts: counts,
+		Count:  h.count.Load(),
+		Sum:    h.sum.Load(),
+	}
+}
+
+// Registry holds named counters, gauges, and histograms so that they can all be exported at once.
+//
+// The zero value is an empty Registry ready to use.
+// A Registry must not be copied after first use.
+type Registry struct {
+	counters   Map[string, *Counter]
+	gauges     Map[string, *Gauge]
+	histograms Map[string, *Histogram]
+}
+
+// RegistrySnapshot is the state of a [Registry] returned by Snapshot.
+type RegistrySnapshot struct {
+	Counters   map[string]int64
+	Gauges     map[string]int64
+	Histograms map[string]HistogramSnapshot
+}
+
+// Counter returns the Counter with the given name, creating it if needed.
+func (r *Registry) Counter(name string) *Counter {
+	if c, ok := r.counters.Get(name); ok {
+		return c
+	}
+	c, _ := r.counters.GetOrSet(name, &Counter{})
+	return c
+}
+
+// Gauge returns the Gauge with the given name, creating it if needed.
+func (r *Registry) Gauge(name string) *Gauge {
+	if g, ok := r.gauges.Get(name); ok {
+		return g
+	}
+	g, _ := r.gauges.GetOrSet(name, &Gauge{})
+	return g
+}
+
+// Histogram returns the Histogram with the given name, creating it with bounds if needed.
+// The bounds are ignored if the Histogram already exists.
+func (r *Registry) Histogram(name string, bounds ...int64) *Histogram {
+	if h, ok := r.histograms.Get(name); ok {
+		return h
+	}
+	h, _ := r.histograms.GetOrSet(name, NewHistogram(bounds...))
+	return h
+}
+
+// Snapshot returns the current values of all the metrics.
+func (r *Registry) Snapshot() RegistrySnapshot {
+	snapshot := RegistrySnapshot{
+		Counters:   make(map[string]int64),
+		Gauges:     make(map[string]int64),
+		Histograms: make(map[string]HistogramSnapshot),
+	}
+	r.counters.Range(func(name string, c *Counter) bool {
+		snapshot.Counters[name] = c.Value()
+		return true
+	})
+	r.gauges.Range(func(name string, g *Gauge) bool {
+		snapshot.Gauges[name] = g.Value()
+		return true
+	})
+	r.histograms.Range(func(name string, h *Histogram) bool {
+		snapshot.Histograms[name] = h.Snapshot()
+		return true
+	})
+	return snapshot
+}
@@ -0,0 +1,56 @@
+package concurrent_test
+
+import (
+	"testing"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestCounterGauge(t *testing.T) {
+	var c concurrent.Counter
+	var g concurrent.Gauge
+	errs := concurrent.GoN(100, func(i int) error {
+		c.Inc()
+		g.Add(1)
+		return nil
+	})
+	must.Nil(t, errs)
+	c.Add(10)
+	must.Eq(t, 110, c.Value())
+	must.Eq(t, 100, g.Value())
+	g.Add(-50)
+	must.Eq(t, 50, g.Value())
+	g.Set(3)
+	must.Eq(t, 3, g.Value())
+}
+
+func TestHistogram(t *testing.T) {
+	h := concurrent.NewHistogram(100, 10)
+	for _, v := range []int64{1, 10, 11, 100, 1000} {
+		h.Observe(v)
+	}
+	must.Eq(t, concurrent.HistogramSnapshot{
+		Bounds: []int64{10, 100},
+		Counts: []int64{2, 2, 1},
+		Count:  5,
+		Sum:    1122,
+	}, h.Snapshot())
+}
+
+func TestRegistry(t *testing.T) {
+	var r concurrent.Registry
+	errs := concurrent.GoN(10, func(i int) error {
+		r.Counter("requests").Inc()
+		r.Histogram("size", 10).Observe(int64(i))
+		return nil
+	})
+	must.Nil(t, errs)
+	r.Gauge("workers").Set(4)
+	must.Eq(t, r.Counter("requests"), r.Counter("requests"))
+
+	snapshot := r.Snapshot()
+	must.Eq(t, map[string]int64{"requests": 10}, snapshot.Counters)
+	must.Eq(t, map[string]int64{"workers": 4}, snapshot.Gauges)
+	must.Eq(t, []int64{10, 0}, snapshot.Histograms["size"].Counts)
+}
@@ -0,0 +1,66 @@
+package concurrent
+
+import "sync/atomic"
+
+// MPSCQueue is a lock-free queue for multiple go routines sending to a single go routine receiving.
+// Push may be called from any number of go routines at once,
+// but only one go routine at a time may call Pop or PopAll.
+//
+// Push never blocks and items are received in the order they were pushed.
+// An item that is being pushed while Pop is called might not be received until the next Pop.
+//
+// Must be constructed with [NewMPSCQueue]
+type MPSCQueue[T any] struct {
+	// head is the last node pushed
+	head atomic.Pointer[mpscNode[T]]
+	// tail is the last node popped, its value has already been received
+	tail *mpscNode[T]
+}
+
+type mpscNode[T any] struct {
+	next  atomic.Pointer[mpscNode[T]]
+	value T
+}
+
+// NewMPSCQueue creates an empty MPSCQueue.
+func NewMPSCQueue[T any]() *MPSCQueue[T] {
+	stub := &mpscNode[T]{}
+	q := &MPSCQueue[T]{tail: stub}
+	q.head.Store(stub)
+	return q
+}
+
+// Push adds an item to the queue.
+// It is safe to call from multiple go routines.
+func (q *MPSCQueue[T]) Push(x T) {
+	n := &mpscNode[T]{value: x}
+	prev := q.head.Swap(n)
+	prev.next.Store(n)
+}
+
+// Pop removes the oldest item without blocking.
+// It returns false if the queue is empty.
+func (q *MPSCQueue[T]) Pop() (T, bool) {
+	var zero T
+	next := q.tail.next.Load()
+	if next == nil {
+		return zero, false
+	}
+	q.tail = next
+	x := next.value
+	next.value = zero
+	return x, true
+}
+
+// PopAll removes all the items and returns them.
+// It returns nil if the queue is empty.
+func (q *MPSCQueue[T]) PopAll() []T {
+	var items []T
+	for {
+		x, ok := q.Pop()
+		if !ok {
+			return items
+		}
+		items = append(items, x)
+	}
+}
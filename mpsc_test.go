@@ -0,0 +1,62 @@
+package concurrent_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestMPSCQueue(t *testing.T) {
+	q := concurrent.NewMPSCQueue[int]()
+	_, ok := q.Pop()
+	must.False(t, ok)
+	must.Nil(t, q.PopAll())
+	q.Push(1)
+	q.Push(2)
+	x, ok := q.Pop()
+	must.True(t, ok)
+	must.Eq(t, 1, x)
+	q.Push(3)
+	must.Eq(t, []int{2, 3}, q.PopAll())
+
+	// each producer's items are received in order
+	const producers, perProducer = 8, 1000
+	done := make(chan []error)
+	go func() {
+		done <- concurrent.GoN(producers, func(p int) error {
+			for i := 0; i < perProducer; i++ {
+				q.Push(p*perProducer + i)
+			}
+			return nil
+		})
+	}()
+	last := make([]int, producers)
+	for i := range last {
+		last[i] = -1
+	}
+	received := 0
+	for received < producers*perProducer {
+		x, ok := q.Pop()
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+		p, i := x/perProducer, x%perProducer
+		must.Greater(t, last[p], i)
+		last[p] = i
+		received++
+	}
+	must.Nil(t, <-done)
+}
+
+func BenchmarkMPSCQueuePush(b *testing.B) {
+	q := concurrent.NewMPSCQueue[int]()
+	b.SetParallelism(32)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			q.Push(1)
+		}
+	})
+}
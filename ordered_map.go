@@ -0,0 +1,100 @@
+package concurrent
+
+import (
+	"container/list"
+	"sync"
+)
+
+// OrderedMap is a map that is safe to use from multiple go routines and remembers the order keys were first set in.
+// This allows results collected concurrently to be emitted deterministically.
+// Functions given to its methods must not call methods of the same OrderedMap.
+//
+// The zero value is an empty OrderedMap ready to use.
+// An OrderedMap must not be copied after first use.
+type OrderedMap[K comparable, V any] struct {
+	mu      sync.RWMutex
+	order   list.List
+	entries map[K]*list.Element
+}
+
+type orderedEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// Get returns the value stored for k.
+// It returns false if there is no value for k.
+func (m *OrderedMap[K, V]) Get(k K) (V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	elem, ok := m.entries[k]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return elem.Value.(*orderedEntry[K, V]).value, true
+}
+
+// Set stores v for k.
+// A new key is ordered last, an existing key keeps its position.
+func (m *OrderedMap[K, V]) Set(k K, v V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if elem, ok := m.entries[k]; ok {
+		elem.Value.(*orderedEntry[K, V]).value = v
+		return
+	}
+	if m.entries == nil {
+		m.entries = make(map[K]*list.Element)
+	}
+	m.entries[k] = m.order.PushBack(&orderedEntry[K, V]{key: k, value: v})
+}
+
+// Delete removes the value for k.
+func (m *OrderedMap[K, V]) Delete(k K) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if elem, ok := m.entries[k]; ok {
+		m.order.Remove(elem)
+		delete(m.entries, k)
+	}
+}
+
+// Len returns the number of values in the OrderedMap.
+func (m *OrderedMap[K, V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.entries)
+}
+
+// Keys returns a copy of the keys in order.
+func (m *OrderedMap[K, V]) Keys() []K {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]K, 0, len(m.entries))
+	for elem := m.order.Front(); elem != nil; elem = elem.Next() {
+		keys = append(keys, elem.Value.(*orderedEntry[K, V]).key)
+	}
+	return keys
+}
+
+// Range calls fn with each key and value in order until fn returns false.
+// It iterates over a snapshot, so fn may modify the OrderedMap
+// but will not see the modifications.
+func (m *OrderedMap[K, V]) Range(fn func(k K, v V) bool) {
+	for _, entry := range m.snapshot() {
+		if !fn(entry.key, entry.value) {
+			return
+		}
+	}
+}
+
+func (m *OrderedMap[K, V]) snapshot() []orderedEntry[K, V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entries := make([]orderedEntry[K, V], 0, len(m.entries))
+	for elem := m.order.Front(); elem != nil; elem = elem.Next() {
+		entries = append(entries, *elem.Value.(*orderedEntry[K, V]))
+	}
+	return entries
+}
@@ -0,0 +1,48 @@
+package concurrent_test
+
+import (
+	"testing"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestOrderedMap(t *testing.T) {
+	var m concurrent.OrderedMap[string, int]
+	_, ok := m.Get("a")
+	must.False(t, ok)
+	m.Delete("a")
+	m.Set("c", 3)
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Set("c", 30)
+	must.Eq(t, []string{"c", "a", "b"}, m.Keys())
+	v, ok := m.Get("c")
+	must.True(t, ok)
+	must.Eq(t, 30, v)
+
+	m.Delete("a")
+	m.Set("a", 10)
+	must.Eq(t, []string{"c", "b", "a"}, m.Keys())
+	must.Eq(t, 3, m.Len())
+
+	var values []int
+	m.Range(func(k string, v int) bool {
+		m.Delete(k)
+		values = append(values, v)
+		return k != "b"
+	})
+	must.Eq(t, []int{30, 2}, values)
+	must.Eq(t, []string{"a"}, m.Keys())
+}
+
+func TestOrderedMapConcurrent(t *testing.T) {
+	var m concurrent.OrderedMap[int, int]
+	errs := concurrent.GoN(100, func(i int) error {
+		m.Set(i%10, i)
+		return nil
+	})
+	must.Nil(t, errs)
+	must.Eq(t, 10, m.Len())
+	must.SliceLen(t, 10, m.Keys())
+}
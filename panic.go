@@ -0,0 +1,235 @@
+package concurrent
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gregwebs/errors"
+	"github.com/gregwebs/go-recovery"
+)
+
+// PanicError is a panic that was recovered and converted to an error by [Recovered].
+// All the functions of this package that recover panics return them as a *PanicError.
+type PanicError struct {
+	value any
+	stack []byte
+}
+
+// Value returns the value that was given to panic.
+func (p *PanicError) Value() any {
+	return p.value
+}
+
+// Stack returns the stack trace of the go routine at the time of the panic.
+// It is nil or limited when configured with [GoRoutine.SetPanicStack].
+func (p *PanicError) Stack() []byte {
+	return p.stack
+}
+
+// Unwrap returns the panic value if it is an error.
+func (p *PanicError) Unwrap() error {
+	if err, ok := p.value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+func (p *PanicError) Error() string {
+	if err := p.Unwrap(); err != nil {
+		return "panic: " + err.Error()
+	}
+	return fmt.Sprintf("panic: %v", p.value)
+}
+
+// As allows retrieving a [recovery.PanicError] with [errors.As]
+// for code that was written for the panic handling of go-recovery.
+func (p *PanicError) As(target any) bool {
+	if pe, ok := target.(*recovery.PanicError); ok {
+		*pe = recovery.PanicError{Panic: p.value}
+		return true
+	}
+	return false
+}
+
+// Format with the extended syntax "%+v" to print the stack trace.
+func (p *PanicError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') && len(p.stack) > 0 {
+			if _, errWrite := fmt.Fprintf(s, "%s\n%s", p.Error(), p.stack); errWrite != nil {
+				errors.HandleWriteError(errWrite)
+			}
+			return
+		}
+		fallthrough
+	case 's', 'q':
+		if _, errWrite := io.WriteString(s, p.Error()); errWrite != nil {
+			errors.HandleWriteError(errWrite)
+		}
+	}
+}
+
+// Recovered calls fn and converts a panic in fn to a [*PanicError].
+// If fn returns an error, that will be returned.
+//
+// An error thrown with [recovery.Throw] is returned as is rather than as a PanicError.
+func Recovered(fn func() error) (err error) {
+	// the returned variable distinguishes a panic from a returned error
+	returned := false
+	defer func() {
+		if !returned {
+			err = toPanicError(recover())
+		}
+	}()
+	err = fn()
+	returned = true
+	return err
+}
+
+// toPanicError must be called from the deferred function that recovered r so that the stack includes the panic.
+// The panic is given to the handler of the go routine set with [GoRoutine.SetPanicHandler] if there is one.
+func toPanicError(r any) error {
+	switch r := r.(type) {
+	// runtime.Goexit
+	case nil:
+		return nil
+	case recovery.ThrownError:
+		return r.Unwrap()
+	case *recovery.ThrownError:
+		if r == nil {
+			return nil
+		}
+		return r.Unwrap()
+	case error:
+		var pe *PanicError
+		if errors.As(r, &pe) {
+			return r
+		}
+	}
+	opts := currentPanicOptions()
+	if opts != nil && opts.handler != nil {
+		return opts.callHandler(r)
+	}
+	return &PanicError{value: r, stack: opts.stack()}
+}
+
+// callHandler converts a panic in the handler to a [*PanicError].
+func (opts *panicOptions) callHandler(r any) (err error) {
+	returned := false
+	defer func() {
+		if !returned {
+			err = &PanicError{value: recover(), stack: opts.stack()}
+		}
+	}()
+	err = opts.handler(r)
+	returned = true
+	return err
+}
+
+// panicOptions configure how a panic is converted to an error in a go routine launched by a GoRoutine.
+// The zero value captures the full stack trace.
+type panicOptions struct {
+	handler      func(recovered any) error
+	stackLimited bool
+	stackDepth   int
+}
+
+// stack returns the stack trace to keep in a PanicError. opts may be nil for the defaults.
+func (opts *panicOptions) stack() []byte {
+	if opts == nil || !opts.stackLimited {
+		return debug.Stack()
+	}
+	if opts.stackDepth == 0 {
+		return nil
+	}
+	return limitStack(debug.Stack(), opts.stackDepth)
+}
+
+// limitStack keeps depth frames of a stack trace in the format of [debug.Stack], starting at the frame that panicked.
+// Each frame is a line with the function followed by a line with the file.
+func limitStack(stack []byte, depth int) []byte {
+	lines := bytes.SplitAfter(stack, []byte("\n"))
+	frames := lines[1:]
+	start := 0
+	for i := 0; i+1 < len(frames); i += 2 {
+		if bytes.HasPrefix(frames[i], []byte("panic(")) {
+			start = i + 2
+			break
+		}
+	}
+	end := min(start+2*depth, len(frames))
+	limited := append([]byte(nil), lines[0]...)
+	for _, line := range frames[start:end] {
+		limited = append(limited, line...)
+	}
+	if end < len(frames) {
+		limited = append(limited, "...additional frames elided...\n"...)
+	}
+	return limited
+}
+
+// A GoRoutine only receives the work that recovers panics, so it cannot pass its panicOptions to it.
+// Instead the options are set for the go routine while it runs the work and looked up when a panic is recovered.
+var (
+	goRoutinePanicOptions sync.Map // go routine id -> *panicOptions
+	goRoutinePanicCount   atomic.Int64
+)
+
+// withPanicOptions returns a GoRoutine whose launched go routines convert panics with the options changed by set.
+// The options of an outer GoRoutine are inherited, so the last option set is used.
+func (gr GoRoutine) withPanicOptions(set func(*panicOptions)) GoRoutine {
+	return GoRoutine(func(work func() error) {
+		gr(func() error {
+			id := goroutineID()
+			var opts panicOptions
+			prev, inherited := goRoutinePanicOptions.Load(id)
+			if inherited {
+				opts = *prev.(*panicOptions)
+			}
+			set(&opts)
+			goRoutinePanicOptions.Store(id, &opts)
+			goRoutinePanicCount.Add(1)
+			defer func() {
+				if inherited {
+					goRoutinePanicOptions.Store(id, prev)
+				} else {
+					goRoutinePanicOptions.Delete(id)
+				}
+				goRoutinePanicCount.Add(-1)
+			}()
+			return work()
+		})
+	})
+}
+
+// currentPanicOptions returns the options of the calling go routine or nil.
+func currentPanicOptions() *panicOptions {
+	if goRoutinePanicCount.Load() == 0 {
+		return nil
+	}
+	if opts, ok := goRoutinePanicOptions.Load(goroutineID()); ok {
+		return opts.(*panicOptions)
+	}
+	return nil
+}
+
+// goroutineID parses the id of the calling go routine from the first line of its stack trace,
+// which is "goroutine 123 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+	line := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	if i := bytes.IndexByte(line, ' '); i >= 0 {
+		line = line[:i]
+	}
+	id, err := strconv.ParseUint(string(line), 10, 64)
+	if err != nil {
+		panic(fmt.Errorf("concurrent: cannot parse the go routine id: %w", err))
+	}
+	return id
+}
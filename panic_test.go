@@ -0,0 +1,50 @@
+package concurrent_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/gregwebs/go-recovery"
+	"github.com/shoenig/test/must"
+)
+
+func TestRecovered(t *testing.T) {
+	must.Nil(t, concurrent.Recovered(func() error { return nil }))
+
+	errReturned := errors.New("returned")
+	must.Eq(t, errReturned, concurrent.Recovered(func() error { return errReturned }))
+
+	err := concurrent.Recovered(func() error { panic("boom") })
+	var pe *concurrent.PanicError
+	must.True(t, errors.As(err, &pe))
+	must.Eq[any](t, "boom", pe.Value())
+	must.Nil(t, pe.Unwrap())
+	must.EqError(t, err, "panic: boom")
+	must.StrContains(t, string(pe.Stack()), "TestRecovered")
+	must.StrContains(t, fmt.Sprintf("%+v", err), "TestRecovered")
+
+	errPanic := errors.New("panic value")
+	err = concurrent.Recovered(func() error { panic(errPanic) })
+	must.ErrorIs(t, err, errPanic)
+	must.EqError(t, err, "panic: panic value")
+
+	// compatible with go-recovery
+	var rpe recovery.PanicError
+	must.True(t, errors.As(err, &rpe))
+	must.Eq[any](t, errPanic, rpe.Panic)
+
+	// a thrown error is not a panic
+	err = concurrent.Recovered(func() error {
+		recovery.Throw(errReturned)
+		return nil
+	})
+	must.ErrorIs(t, err, errReturned)
+	must.False(t, errors.As(err, &pe))
+
+	// a PanicError that panics again is not wrapped again
+	inner := concurrent.Recovered(func() error { panic("inner") })
+	err = concurrent.Recovered(func() error { panic(inner) })
+	must.Eq(t, inner, err)
+}
@@ -0,0 +1,67 @@
+package concurrent
+
+import (
+	"sync"
+)
+
+// Pool runs functions on a fixed set of long-lived worker go routines.
+// This amortizes the cost of starting go routines for code that performs many small fan-outs,
+// such as request handlers.
+//
+// Launching blocks until a worker is idle.
+// So a function running in the Pool must not wait for other functions launched in the same Pool:
+// if every worker is waiting, nothing can make progress.
+//
+// Must be constructed with [NewPool]
+type Pool struct {
+	tasks chan func() error
+	wg    sync.WaitGroup
+}
+
+// NewPool starts a [Pool] with the given number of workers.
+// Call [*Pool.Close] to stop the workers.
+// workers must be positive.
+func NewPool(workers int) *Pool {
+	if workers < 1 {
+		panic("concurrent: workers must be positive")
+	}
+	p := &Pool{tasks: make(chan func() error)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for task := range p.tasks {
+		_ = task()
+	}
+}
+
+// GoRoutine returns a [GoRoutine] that launches functions on the workers of the Pool.
+// Use it for the other GoRoutine APIs:
+//
+//	GoEachRoutine(items, fn)(pool.GoRoutine())
+func (p *Pool) GoRoutine() GoRoutine {
+	return GoRoutine(func(work func() error) { p.tasks <- work })
+}
+
+// The same as [GoN] but runs on the workers of the Pool.
+func (p *Pool) GoN(n int, fn func(int) error) []error {
+	return p.GoRoutine().GoN(n, fn)
+}
+
+// GoEachPool is the same as [GoEach] but runs on the workers of the Pool.
+// It is a function rather than a method of Pool because methods cannot have type parameters.
+func GoEachPool[T any](p *Pool, all []T, fn func(T) error) []error {
+	return GoEachRoutine(all, fn)(p.GoRoutine())
+}
+
+// Close stops the workers after they finish the functions they are running.
+// The Pool must not be used after Close.
+func (p *Pool) Close() {
+	close(p.tasks)
+	p.wg.Wait()
+}
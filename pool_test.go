@@ -0,0 +1,64 @@
+package concurrent_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestPool(t *testing.T) {
+	pool := concurrent.NewPool(3)
+	defer pool.Close()
+
+	for round := 0; round < 10; round++ {
+		var active, maxActive int32
+		tracked := make([]bool, 20)
+		err := pool.GoN(len(tracked), func(i int) error {
+			n := atomic.AddInt32(&active, 1)
+			for {
+				m := atomic.LoadInt32(&maxActive)
+				if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+					break
+				}
+			}
+			tracked[i] = true
+			atomic.AddInt32(&active, -1)
+			return nil
+		})
+		must.Nil(t, err)
+		must.LessEq(t, 3, atomic.LoadInt32(&maxActive))
+		for _, done := range tracked {
+			must.True(t, done)
+		}
+	}
+
+	errBad := errors.New("bad")
+	err := concurrent.GoEachPool(pool, []int{1, 2, 3}, func(x int) error {
+		if x == 2 {
+			return errBad
+		}
+		if x == 3 {
+			panic("three")
+		}
+		return nil
+	})
+	must.Len(t, 2, err)
+	must.Eq(t, errBad, err[0])
+
+	// the workers survive panics
+	must.Nil(t, pool.GoN(3, func(_ int) error { return nil }))
+}
+
+func TestNewPoolWorkers(t *testing.T) {
+	// without workers every launch would block forever
+	for _, workers := range []int{0, -1} {
+		err := concurrent.Recovered(func() error {
+			concurrent.NewPool(workers)
+			return nil
+		})
+		must.Error(t, err)
+	}
+}
@@ -0,0 +1,116 @@
+package concurrent
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// PriorityQueue is a queue that is safe to use from multiple go routines
+// where the item with the highest priority is received first.
+// Items with the same priority are received in the order they were pushed.
+//
+// The zero value is an empty PriorityQueue ready to use.
+// A PriorityQueue must not be copied after first use.
+type PriorityQueue[T any] struct {
+	mu    sync.Mutex
+	items priorityHeap[T]
+	// seq orders items with the same priority
+	seq uint64
+	// wake is closed to wake up the go routines waiting in Pop.
+	// It is nil when none are waiting.
+	wake chan struct{}
+}
+
+// Push adds an item with the given priority.
+func (pq *PriorityQueue[T]) Push(item T, priority int) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	heap.Push(&pq.items, prioritized[T]{item: item, priority: priority, seq: pq.seq})
+	pq.seq++
+	if pq.wake != nil {
+		close(pq.wake)
+		pq.wake = nil
+	}
+}
+
+// TryPop removes the item with the highest priority without blocking.
+// It returns false if the PriorityQueue is empty.
+func (pq *PriorityQueue[T]) TryPop() (T, bool) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	return pq.pop()
+}
+
+// Pop removes the item with the highest priority, waiting for one to be pushed if the PriorityQueue is empty.
+// It returns the cause of ctx if ctx is done before an item is pushed.
+func (pq *PriorityQueue[T]) Pop(ctx context.Context) (T, error) {
+	for {
+		pq.mu.Lock()
+		if x, ok := pq.pop(); ok {
+			pq.mu.Unlock()
+			return x, nil
+		}
+		if pq.wake == nil {
+			pq.wake = make(chan struct{})
+		}
+		wake := pq.wake
+		pq.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			var zero T
+			return zero, context.Cause(ctx)
+		}
+	}
+}
+
+// Len returns the number of items in the PriorityQueue.
+func (pq *PriorityQueue[T]) Len() int {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	return len(pq.items)
+}
+
+// pop must be called with the lock held.
+func (pq *PriorityQueue[T]) pop() (T, bool) {
+	if len(pq.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	return heap.Pop(&pq.items).(prioritized[T]).item, true
+}
+
+type prioritized[T any] struct {
+	item     T
+	priority int
+	seq      uint64
+}
+
+// priorityHeap implements heap.Interface
+type priorityHeap[T any] []prioritized[T]
+
+func (h priorityHeap[T]) Len() int { return len(h) }
+
+func (h priorityHeap[T]) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h priorityHeap[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *priorityHeap[T]) Push(x any) {
+	*h = append(*h, x.(prioritized[T]))
+}
+
+func (h *priorityHeap[T]) Pop() any {
+	old := *h
+	last := len(old) - 1
+	x := old[last]
+	old[last] = prioritized[T]{}
+	*h = old[:last]
+	return x
+}
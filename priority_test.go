@@ -0,0 +1,43 @@
+package concurrent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	var pq concurrent.PriorityQueue[string]
+	_, ok := pq.TryPop()
+	must.False(t, ok)
+	pq.Push("low", 1)
+	pq.Push("high", 10)
+	pq.Push("medium first", 5)
+	pq.Push("medium second", 5)
+	must.Eq(t, 4, pq.Len())
+	for _, want := range []string{"high", "medium first", "medium second", "low"} {
+		x, ok := pq.TryPop()
+		must.True(t, ok)
+		must.Eq(t, want, x)
+	}
+	must.Eq(t, 0, pq.Len())
+}
+
+func TestPriorityQueuePop(t *testing.T) {
+	var pq concurrent.PriorityQueue[int]
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err := pq.Pop(ctx)
+	must.ErrorIs(t, err, context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(time.Millisecond)
+		pq.Push(1, 0)
+	}()
+	x, err := pq.Pop(context.Background())
+	must.NoError(t, err)
+	must.Eq(t, 1, x)
+}
@@ -0,0 +1,77 @@
+package concurrent
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// RCU holds a value that readers get without locking and writers replace by publishing a new version,
+// in the style of read-copy-update.
+// An old version is given to the reclaim function only after all the readers that acquired it have released it,
+// so a version can hold resources such as connections that must be closed once unused.
+//
+// Must be constructed with [NewRCU]
+type RCU[T any] struct {
+	current atomic.Pointer[rcuVersion[T]]
+	reclaim func(*T)
+}
+
+type rcuVersion[T any] struct {
+	value     *T
+	readers   atomic.Int64
+	retired   atomic.Bool
+	reclaimed sync.Once
+}
+
+// NewRCU creates an RCU holding initial.
+// reclaim is called once for each version that has been replaced and released by all readers.
+// It may be nil.
+func NewRCU[T any](initial *T, reclaim func(old *T)) *RCU[T] {
+	r := &RCU[T]{reclaim: reclaim}
+	r.current.Store(&rcuVersion[T]{value: initial})
+	return r
+}
+
+// Acquire returns the current version and a function that must be called once the version is no longer used.
+func (r *RCU[T]) Acquire() (value *T, release func()) {
+	for {
+		v := r.current.Load()
+		v.readers.Add(1)
+		// if the version was replaced before it was counted, it might already be reclaimed
+		if r.current.Load() == v {
+			return v.value, func() { r.release(v) }
+		}
+		r.release(v)
+	}
+}
+
+// Read calls fn with the current version, which is released once fn returns.
+func (r *RCU[T]) Read(fn func(*T)) {
+	value, release := r.Acquire()
+	defer release()
+	fn(value)
+}
+
+// Publish makes value the current version.
+// The previous version is reclaimed once all of its readers have released it, possibly right away.
+func (r *RCU[T]) Publish(value *T) {
+	old := r.current.Swap(&rcuVersion[T]{value: value})
+	old.retired.Store(true)
+	if old.readers.Load() == 0 {
+		r.reclaimVersion(old)
+	}
+}
+
+func (r *RCU[T]) release(v *rcuVersion[T]) {
+	if v.readers.Add(-1) == 0 && v.retired.Load() {
+		r.reclaimVersion(v)
+	}
+}
+
+func (r *RCU[T]) reclaimVersion(v *rcuVersion[T]) {
+	v.reclaimed.Do(func() {
+		if r.reclaim != nil {
+			r.reclaim(v.value)
+		}
+	})
+}
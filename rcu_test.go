@@ -0,0 +1,55 @@
+package concurrent_test
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+type rcuConn struct {
+	id     int
+	closed atomic.Bool
+}
+
+func TestRCU(t *testing.T) {
+	var reclaimed []int
+	r := concurrent.NewRCU(&rcuConn{id: 1}, func(old *rcuConn) {
+		old.closed.Store(true)
+		reclaimed = append(reclaimed, old.id)
+	})
+	first, release := r.Acquire()
+	must.Eq(t, 1, first.id)
+
+	r.Publish(&rcuConn{id: 2})
+	must.SliceEmpty(t, reclaimed)
+	r.Read(func(c *rcuConn) {
+		must.Eq(t, 2, c.id)
+	})
+	release()
+	must.Eq(t, []int{1}, reclaimed)
+
+	r.Publish(&rcuConn{id: 3})
+	must.Eq(t, []int{1, 2}, reclaimed)
+}
+
+func TestRCUConcurrent(t *testing.T) {
+	var reclaimed atomic.Int64
+	r := concurrent.NewRCU(&rcuConn{}, func(old *rcuConn) {
+		old.closed.Store(true)
+		reclaimed.Add(1)
+	})
+	errs := concurrent.GoN(200, func(i int) error {
+		if i%10 == 0 {
+			r.Publish(&rcuConn{id: i})
+			return nil
+		}
+		r.Read(func(c *rcuConn) {
+			must.False(t, c.closed.Load())
+		})
+		return nil
+	})
+	must.Nil(t, errs)
+	must.Eq(t, 20, reclaimed.Load())
+}
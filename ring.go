@@ -0,0 +1,60 @@
+package concurrent
+
+import "sync"
+
+// Ring holds the last items added to it, overwriting the oldest item once it is full.
+// It is safe to use from multiple go routines and is useful for recording recent errors or samples.
+// Unlike a Slice created by [NewSliceCap], it allocates its storage once.
+//
+// Must be constructed with [NewRing]
+type Ring[T any] struct {
+	mu   sync.RWMutex
+	buf  []T
+	next int
+	full bool
+}
+
+// NewRing creates a Ring that holds the last size items.
+// size must be positive.
+func NewRing[T any](size int) *Ring[T] {
+	if size < 1 {
+		panic("concurrent: size must be positive")
+	}
+	return &Ring[T]{buf: make([]T, size)}
+}
+
+// Add adds items, overwriting the oldest items once the Ring is full.
+func (r *Ring[T]) Add(items ...T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, x := range items {
+		r.buf[r.next] = x
+		r.next++
+		if r.next == len(r.buf) {
+			r.next = 0
+			r.full = true
+		}
+	}
+}
+
+// Len returns the number of items in the Ring, which is at most its size.
+func (r *Ring[T]) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.full {
+		return len(r.buf)
+	}
+	return r.next
+}
+
+// Snapshot returns a copy of the items from oldest to newest.
+func (r *Ring[T]) Snapshot() []T {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.full {
+		return append([]T(nil), r.buf[:r.next]...)
+	}
+	items := make([]T, 0, len(r.buf))
+	items = append(items, r.buf[r.next:]...)
+	return append(items, r.buf[:r.next]...)
+}
@@ -0,0 +1,32 @@
+package concurrent_test
+
+import (
+	"testing"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestRing(t *testing.T) {
+	r := concurrent.NewRing[int](3)
+	must.Eq(t, 0, r.Len())
+	must.SliceEmpty(t, r.Snapshot())
+	r.Add(1, 2)
+	must.Eq(t, 2, r.Len())
+	must.Eq(t, []int{1, 2}, r.Snapshot())
+	r.Add(3)
+	must.Eq(t, []int{1, 2, 3}, r.Snapshot())
+	r.Add(4, 5)
+	must.Eq(t, 3, r.Len())
+	must.Eq(t, []int{3, 4, 5}, r.Snapshot())
+	r.Add(6, 7, 8, 9)
+	must.Eq(t, []int{7, 8, 9}, r.Snapshot())
+
+	r = concurrent.NewRing[int](10)
+	errs := concurrent.GoN(100, func(i int) error {
+		r.Add(i)
+		return nil
+	})
+	must.Nil(t, errs)
+	must.Eq(t, 10, r.Len())
+}
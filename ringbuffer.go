@@ -0,0 +1,119 @@
+package concurrent
+
+// ringBuffer is a FIFO queue stored in a circular slice that grows when full and shrinks when mostly empty.
+// Unlike re-slicing a slice to remove the first item, it reuses the space of removed items
+// and does not keep a large backing array alive after the queue has been emptied.
+// The zero value is an empty ringBuffer.
+type ringBuffer[T any] struct {
+	buf  []T
+	head int
+	n    int
+}
+
+const (
+	ringBufferMinSize = 16
+	// buffers smaller than this are kept so that bursts do not repeatedly grow and shrink the buffer
+	ringBufferShrinkSize = 1024
+)
+
+// ringBufferOf returns a ringBuffer holding items, which it takes ownership of.
+func ringBufferOf[T any](items []T) ringBuffer[T] {
+	return ringBuffer[T]{buf: items, n: len(items)}
+}
+
+func (r *ringBuffer[T]) len() int {
+	return r.n
+}
+
+func (r *ringBuffer[T]) push(x T) {
+	if r.n == len(r.buf) {
+		r.resize(max(2*len(r.buf), ringBufferMinSize))
+	}
+	i := r.head + r.n
+	if i >= len(r.buf) {
+		i -= len(r.buf)
+	}
+	r.buf[i] = x
+	r.n++
+}
+
+func (r *ringBuffer[T]) pop() (T, bool) {
+	var zero T
+	if r.n == 0 {
+		return zero, false
+	}
+	x := r.buf[r.head]
+	r.buf[r.head] = zero
+	r.head++
+	if r.head == len(r.buf) {
+		r.head = 0
+	}
+	r.n--
+	if len(r.buf) >= ringBufferShrinkSize && r.n <= len(r.buf)/4 {
+		r.resize(len(r.buf) / 2)
+	}
+	return x, true
+}
+
+// appendTo appends the items in order to dst.
+func (r *ringBuffer[T]) appendTo(dst []T) []T {
+	end := r.head + r.n
+	if end <= len(r.buf) {
+		return append(dst, r.buf[r.head:end]...)
+	}
+	dst = append(dst, r.buf[r.head:]...)
+	return append(dst, r.buf[:end-len(r.buf)]...)
+}
+
+// at returns the item at index i, which must be in range.
+func (r *ringBuffer[T]) at(i int) *T {
+	i += r.head
+	if i >= len(r.buf) {
+		i -= len(r.buf)
+	}
+	return &r.buf[i]
+}
+
+// copyTo copies the items in order into dst like the built-in copy.
+func (r *ringBuffer[T]) copyTo(dst []T) int {
+	end := r.head + r.n
+	if end <= len(r.buf) {
+		return copy(dst, r.buf[r.head:end])
+	}
+	n := copy(dst, r.buf[r.head:])
+	return n + copy(dst[n:], r.buf[:end-len(r.buf)])
+}
+
+// linear returns the items as a slice that shares the buffer, moving the items if they wrap around.
+// The slice is only valid until the next change.
+func (r *ringBuffer[T]) linear() []T {
+	if r.head+r.n > len(r.buf) {
+		r.resize(len(r.buf))
+	}
+	return r.buf[r.head : r.head+r.n]
+}
+
+// insertAt inserts x at index i, shifting the following items up.
+func (r *ringBuffer[T]) insertAt(i int, x T) {
+	r.push(x)
+	items := r.linear()
+	copy(items[i+1:], items[i:])
+	items[i] = x
+}
+
+// removeAt removes the item at index i, shifting the following items down.
+func (r *ringBuffer[T]) removeAt(i int) T {
+	items := r.linear()
+	x := items[i]
+	copy(items[i:], items[i+1:])
+	var zero T
+	items[len(items)-1] = zero
+	r.n--
+	return x
+}
+
+func (r *ringBuffer[T]) resize(size int) {
+	buf := r.appendTo(make([]T, 0, size))
+	r.buf = buf[:size]
+	r.head = 0
+}
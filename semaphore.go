@@ -0,0 +1,148 @@
+package concurrent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Semaphore limits the use of a resource by a total weight rather than by a count:
+// each acquisition can take multiple slots of the size.
+// By default waiters are served in FIFO order so that a large acquisition is not starved by small ones.
+// This can be changed with [Semaphore.SetFIFO].
+//
+// Must be constructed with [NewSemaphore]
+type Semaphore struct {
+	mu      sync.Mutex
+	size    int64
+	cur     int64
+	waiters []semaphoreWaiter
+	// barging lets an acquisition that fits take slots ahead of the waiters
+	barging bool
+}
+
+type semaphoreWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// NewSemaphore creates a Semaphore with size slots.
+// It panics if size is negative.
+func NewSemaphore(size int64) *Semaphore {
+	if size < 0 {
+		panic(fmt.Errorf("semaphore: size %d is negative", size))
+	}
+	return &Semaphore{size: size}
+}
+
+// SetFIFO sets whether waiters are served in FIFO order, which is the default.
+// Without FIFO order an acquisition that fits in the available slots succeeds even if others are waiting,
+// which gives more throughput but can starve large acquisitions.
+func (s *Semaphore) SetFIFO(fifo bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.barging = !fifo
+	s.notifyWaiters()
+}
+
+// Acquire takes n slots, waiting until they are available.
+// It returns the cause of ctx if ctx is done first, in which case no slots are taken.
+// It panics if n is negative or larger than the size.
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	checkSemaphoreN(n)
+	s.mu.Lock()
+	if n > s.size {
+		s.mu.Unlock()
+		panic(fmt.Errorf("semaphore: acquire %d is larger than the size %d", n, s.size))
+	}
+	if s.fits(n) {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	s.waiters = append(s.waiters, semaphoreWaiter{n: n, ready: ready})
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-ready:
+		// the slots were given while ctx was done: keep them rather than undoing the notification
+		return nil
+	default:
+	}
+	for i, w := range s.waiters {
+		if w.ready == ready {
+			s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+			break
+		}
+	}
+	// waiters that were queued behind this one might fit now
+	s.notifyWaiters()
+	return context.Cause(ctx)
+}
+
+// TryAcquire takes n slots without waiting.
+// It returns false if the slots are not available, or if others are waiting with FIFO order.
+// It panics if n is negative.
+func (s *Semaphore) TryAcquire(n int64) bool {
+	checkSemaphoreN(n)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n > s.size || !s.fits(n) {
+		return false
+	}
+	s.cur += n
+	return true
+}
+
+// Release gives back n slots taken by Acquire or TryAcquire.
+// It panics if n is negative or if more slots are released than are held.
+func (s *Semaphore) Release(n int64) {
+	checkSemaphoreN(n)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cur -= n
+	if s.cur < 0 {
+		panic("semaphore: released more than held")
+	}
+	s.notifyWaiters()
+}
+
+// checkSemaphoreN panics if n is negative, which would add slots instead of taking them.
+func checkSemaphoreN(n int64) {
+	if n < 0 {
+		panic(fmt.Errorf("semaphore: n %d is negative", n))
+	}
+}
+
+// fits must be called with the lock held.
+func (s *Semaphore) fits(n int64) bool {
+	return s.size-s.cur >= n && (s.barging || len(s.waiters) == 0)
+}
+
+// notifyWaiters gives the available slots to the waiters.
+// It must be called with the lock held.
+func (s *Semaphore) notifyWaiters() {
+	waiting := s.waiters[:0]
+	for i, next := range s.waiters {
+		if s.size-s.cur < next.n {
+			if !s.barging {
+				waiting = append(waiting, s.waiters[i:]...)
+				break
+			}
+			waiting = append(waiting, next)
+			continue
+		}
+		s.cur += next.n
+		close(next.ready)
+	}
+	clear(s.waiters[len(waiting):])
+	s.waiters = waiting
+}
@@ -0,0 +1,84 @@
+package concurrent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestSemaphore(t *testing.T) {
+	ctx := context.Background()
+	sem := concurrent.NewSemaphore(3)
+	must.NoError(t, sem.Acquire(ctx, 2))
+	must.True(t, sem.TryAcquire(1))
+	must.False(t, sem.TryAcquire(1))
+
+	acquired := make(chan struct{})
+	go func() {
+		must.NoError(t, sem.Acquire(ctx, 2))
+		close(acquired)
+	}()
+	sem.Release(1)
+	select {
+	case <-acquired:
+		t.Fatal("acquired before enough slots were released")
+	case <-time.After(10 * time.Millisecond):
+	}
+	sem.Release(2)
+	<-acquired
+	sem.Release(2)
+	must.True(t, sem.TryAcquire(3))
+}
+
+func TestSemaphoreNegative(t *testing.T) {
+	ctx := context.Background()
+	sem := concurrent.NewSemaphore(1)
+	// a negative n would add slots instead of taking them
+	for _, fn := range []func(){
+		func() { concurrent.NewSemaphore(-1) },
+		func() { _ = sem.Acquire(ctx, -1) },
+		func() { sem.TryAcquire(-1) },
+		func() { sem.Release(-1) },
+	} {
+		err := concurrent.Recovered(func() error {
+			fn()
+			return nil
+		})
+		must.Error(t, err)
+	}
+	must.True(t, sem.TryAcquire(1))
+	must.False(t, sem.TryAcquire(1))
+}
+
+func TestSemaphoreAcquireContext(t *testing.T) {
+	sem := concurrent.NewSemaphore(2)
+	must.True(t, sem.TryAcquire(2))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	must.ErrorIs(t, sem.Acquire(ctx, 1), context.DeadlineExceeded)
+	sem.Release(2)
+	// the canceled acquisition took no slots
+	must.True(t, sem.TryAcquire(2))
+}
+
+func TestSemaphoreFIFO(t *testing.T) {
+	ctx := context.Background()
+	sem := concurrent.NewSemaphore(2)
+	must.True(t, sem.TryAcquire(1))
+	large := make(chan struct{})
+	go func() {
+		must.NoError(t, sem.Acquire(ctx, 2))
+		close(large)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	// a small acquisition that fits waits behind the large one
+	must.False(t, sem.TryAcquire(1))
+
+	sem.SetFIFO(false)
+	must.True(t, sem.TryAcquire(1))
+	sem.Release(2)
+	<-large
+}
@@ -0,0 +1,124 @@
+package concurrent
+
+import "math/rand/v2"
+
+// ShardedUnboundedChan spreads its items over multiple [UnboundedChan] shards
+// so that many go routines sending at once do not contend on a single lock.
+// Each Send goes to a random shard and Recv looks through the shards starting at a random one,
+// so items are not received in the order they were sent.
+//
+// Must be constructed with [NewUnboundedChanSharded]
+type ShardedUnboundedChan[T any] struct {
+	// each UnboundedChan points to its own allocation, so the shards do not need padding
+	shards []UnboundedChan[T]
+}
+
+// NewUnboundedChanSharded creates a ShardedUnboundedChan with the given number of shards.
+// shards must be positive.
+func NewUnboundedChanSharded[T any](shards int) ShardedUnboundedChan[T] {
+	if shards < 1 {
+		panic("concurrent: shards must be positive")
+	}
+	sc := ShardedUnboundedChan[T]{shards: make([]UnboundedChan[T], shards)}
+	for i := range sc.shards {
+		sc.shards[i] = NewUnboundedChan[T]()
+	}
+	return sc
+}
+
+// Send adds an item to a shard.
+// Like a channel, it panics if the ShardedUnboundedChan has been closed by Drain.
+func (sc ShardedUnboundedChan[T]) Send(x T) {
+	sc.shards[rand.IntN(len(sc.shards))].Send(x)
+}
+
+// Recv removes an item from one of the shards without blocking.
+// It returns false if all the shards are empty.
+func (sc ShardedUnboundedChan[T]) Recv() (T, bool) {
+	start := rand.IntN(len(sc.shards))
+	for i := range sc.shards {
+		if x, ok := sc.shards[(start+i)%len(sc.shards)].Recv(); ok {
+			return x, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// Len returns the number of items in all the shards.
+func (sc ShardedUnboundedChan[T]) Len() int {
+	n := 0
+	for _, shard := range sc.shards {
+		n += shard.Stats().Depth
+	}
+	return n
+}
+
+// Drain closes all the shards and returns the items that have not been received.
+func (sc ShardedUnboundedChan[T]) Drain() []T {
+	var items []T
+	for _, shard := range sc.shards {
+		items = shard.DrainTo(items)
+	}
+	return items
+}
+
+// ShardedSlice spreads its items over multiple [Slice] shards
+// so that many go routines appending at once do not contend on a single lock.
+// Each Append goes to a random shard, so the order of the items is not preserved.
+//
+// Must be constructed with [NewSliceSharded]
+type ShardedSlice[T any] struct {
+	shards []sliceShard[T]
+}
+
+// cacheLineSize is the size of a cache line on common CPUs.
+const cacheLineSize = 64
+
+// sliceShard pads a Slice so that the locks of neighbouring shards are not on the same cache line.
+type sliceShard[T any] struct {
+	Slice[T]
+	_ [cacheLineSize]byte
+}
+
+// NewSliceSharded creates a ShardedSlice with the given number of shards.
+// shards must be positive.
+func NewSliceSharded[T any](shards int) *ShardedSlice[T] {
+	if shards < 1 {
+		panic("concurrent: shards must be positive")
+	}
+	return &ShardedSlice[T]{shards: make([]sliceShard[T], shards)}
+}
+
+// Append adds items to a shard.
+func (ss *ShardedSlice[T]) Append(items ...T) {
+	ss.shards[rand.IntN(len(ss.shards))].Append(items...)
+}
+
+// Len returns the number of items in all the shards.
+func (ss *ShardedSlice[T]) Len() int {
+	n := 0
+	for i := range ss.shards {
+		n += ss.shards[i].Len()
+	}
+	return n
+}
+
+// Snapshot returns a copy of the items of all the shards.
+// Each shard is copied separately, so the result is not a single point in time snapshot.
+func (ss *ShardedSlice[T]) Snapshot() []T {
+	var items []T
+	for i := range ss.shards {
+		items = ss.shards[i].appendTo(items)
+	}
+	return items
+}
+
+// TakeAll removes all the items from all the shards and returns them.
+func (ss *ShardedSlice[T]) TakeAll() []T {
+	var items []T
+	for i := range ss.shards {
+		items = append(items, ss.shards[i].TakeAll()...)
+	}
+	return items
+}
@@ -0,0 +1,93 @@
+package concurrent_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestShardedUnboundedChan(t *testing.T) {
+	sc := concurrent.NewUnboundedChanSharded[int](4)
+	_, ok := sc.Recv()
+	must.False(t, ok)
+	errs := concurrent.GoN(100, func(i int) error {
+		sc.Send(i)
+		return nil
+	})
+	must.Nil(t, errs)
+	must.Eq(t, 100, sc.Len())
+
+	var received []int
+	for i := 0; i < 50; i++ {
+		x, ok := sc.Recv()
+		must.True(t, ok)
+		received = append(received, x)
+	}
+	must.Eq(t, 50, sc.Len())
+	received = append(received, sc.Drain()...)
+	slices.Sort(received)
+	for i, x := range received {
+		must.Eq(t, i, x)
+	}
+	must.Eq(t, 0, sc.Len())
+}
+
+func TestShardedSlice(t *testing.T) {
+	ss := concurrent.NewSliceSharded[int](4)
+	errs := concurrent.GoN(100, func(i int) error {
+		ss.Append(i)
+		return nil
+	})
+	must.Nil(t, errs)
+	must.Eq(t, 100, ss.Len())
+	must.SliceLen(t, 100, ss.Snapshot())
+
+	items := ss.TakeAll()
+	slices.Sort(items)
+	for i, x := range items {
+		must.Eq(t, i, x)
+	}
+	must.Eq(t, 0, ss.Len())
+}
+
+func BenchmarkUnboundedChanSend(b *testing.B) {
+	uc := concurrent.NewUnboundedChan[int]()
+	b.SetParallelism(32)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			uc.Send(1)
+		}
+	})
+}
+
+func BenchmarkShardedUnboundedChanSend(b *testing.B) {
+	sc := concurrent.NewUnboundedChanSharded[int](32)
+	b.SetParallelism(32)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			sc.Send(1)
+		}
+	})
+}
+
+func BenchmarkSliceAppend(b *testing.B) {
+	var s concurrent.Slice[int]
+	b.SetParallelism(32)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.Append(1)
+		}
+	})
+}
+
+func BenchmarkShardedSliceAppend(b *testing.B) {
+	ss := concurrent.NewSliceSharded[int](32)
+	b.SetParallelism(32)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ss.Append(1)
+		}
+	})
+}
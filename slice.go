@@ -0,0 +1,250 @@
+package concurrent
+
+import (
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Slice is a slice that is safe to use from multiple go routines.
+// Functions given to its methods must not call methods of the same Slice.
+//
+// A capacity can be given with [NewSliceCap].
+//
+// The zero value is an empty Slice ready to use.
+// A Slice must not be copied after first use.
+type Slice[T any] struct {
+	mu sync.RWMutex
+	// a ringBuffer lets Shift remove the first item without keeping the removed space allocated
+	items ringBuffer[T]
+	// capacity is 0 when unbounded
+	capacity int
+	policy   OverflowPolicy
+}
+
+// NewSlice creates a Slice containing a copy of items.
+func NewSlice[T any](items ...T) *Slice[T] {
+	return &Slice[T]{items: ringBufferOf(slices.Clone(items))}
+}
+
+// NewSliceCap creates an empty Slice that holds at most capacity items,
+// which is useful for tracking the last N events.
+// When an Append goes beyond the capacity, the [DropOldest] policy removes the first items
+// and the [DropNewest] policy discards the items that do not fit.
+// capacity must be positive and the [Block] policy is not supported.
+func NewSliceCap[T any](capacity int, policy OverflowPolicy) *Slice[T] {
+	if capacity < 1 {
+		panic("concurrent: capacity must be positive")
+	}
+	if policy != DropOldest && policy != DropNewest {
+		panic("concurrent: Slice supports only the DropOldest and DropNewest policies")
+	}
+	return &Slice[T]{capacity: capacity, policy: policy}
+}
+
+// Append adds items to the end of the Slice.
+func (s *Slice[T]) Append(items ...T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.capacity > 0 {
+		if s.policy == DropNewest {
+			items = items[:min(len(items), s.capacity-s.items.len())]
+		} else {
+			// only the last capacity items can remain
+			items = items[max(0, len(items)-s.capacity):]
+		}
+	}
+	for _, x := range items {
+		// dropping before pushing keeps the ring buffer at capacity so a full Slice does not allocate
+		if s.capacity > 0 && s.items.len() == s.capacity {
+			s.items.pop()
+		}
+		s.items.push(x)
+	}
+}
+
+// Len returns the number of items in the Slice.
+func (s *Slice[T]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.items.len()
+}
+
+// Get returns the item at index i.
+// It returns false if i is out of range.
+func (s *Slice[T]) Get(i int) (T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if i < 0 || i >= s.items.len() {
+		var zero T
+		return zero, false
+	}
+	return *s.items.at(i), true
+}
+
+// Set replaces the item at index i.
+// It returns false if i is out of range.
+func (s *Slice[T]) Set(i int, x T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i < 0 || i >= s.items.len() {
+		return false
+	}
+	*s.items.at(i) = x
+	return true
+}
+
+// Delete removes the item at index i, shifting the following items down.
+// It returns false if i is out of range.
+func (s *Slice[T]) Delete(i int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i < 0 || i >= s.items.len() {
+		return false
+	}
+	s.items.removeAt(i)
+	return true
+}
+
+// Insert inserts x at index i, shifting the following items up.
+// i may be Len() to append.
+// It returns false if i is out of range.
+//
+// If the Slice is at capacity, the [DropNewest] policy returns false
+// and the [DropOldest] policy removes the first item before inserting x at i-1.
+func (s *Slice[T]) Insert(i int, x T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i < 0 || i > s.items.len() {
+		return false
+	}
+	if s.capacity > 0 && s.items.len() == s.capacity {
+		if s.policy == DropNewest {
+			return false
+		}
+		s.items.pop()
+		i = max(i-1, 0)
+	}
+	s.items.insertAt(i, x)
+	return true
+}
+
+// RemoveAt removes and returns the item at index i, shifting the following items down.
+// It returns false if i is out of range.
+func (s *Slice[T]) RemoveAt(i int) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i < 0 || i >= s.items.len() {
+		var zero T
+		return zero, false
+	}
+	return s.items.removeAt(i), true
+}
+
+// Contains reports whether an item satisfies eq.
+func (s *Slice[T]) Contains(eq func(T) bool) bool {
+	return s.IndexFunc(eq) >= 0
+}
+
+// IndexFunc returns the index of the first item satisfying fn, or -1 if none do.
+func (s *Slice[T]) IndexFunc(fn func(T) bool) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := range s.items.len() {
+		if fn(*s.items.at(i)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Sort sorts the items in place.
+// The sort is not guaranteed to be stable.
+func (s *Slice[T]) Sort(less func(a, b T) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := s.items.linear()
+	sort.Slice(items, func(i, j int) bool {
+		return less(items[i], items[j])
+	})
+}
+
+// Shift removes and returns the first item.
+// It returns false if the Slice is empty.
+func (s *Slice[T]) Shift() (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.items.pop()
+}
+
+// TakeAll removes all the items and returns them.
+func (s *Slice[T]) TakeAll() []T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := s.items.appendTo(nil)
+	s.items = ringBuffer[T]{}
+	return items
+}
+
+// Snapshot returns a copy of the items.
+func (s *Slice[T]) Snapshot() []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.items.appendTo(nil)
+}
+
+// Clone returns a new Slice with a copy of the items and the same capacity.
+func (s *Slice[T]) Clone() *Slice[T] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.withItems(s.items.appendTo(nil))
+}
+
+// withItems returns a new Slice of items, which it takes ownership of, with the same capacity as s.
+func (s *Slice[T]) withItems(items []T) *Slice[T] {
+	return &Slice[T]{items: ringBufferOf(items), capacity: s.capacity, policy: s.policy}
+}
+
+// CopyTo copies items into dst like the built-in copy and returns the number of items copied.
+// It does not allocate, so a buffer can be reused.
+func (s *Slice[T]) CopyTo(dst []T) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.items.copyTo(dst)
+}
+
+// MapConcurrent runs fn in a go routine for each item of a Snapshot and returns a new Slice of the results
+// with the same capacity, like [GoNOrdered].
+// If fn returns an error or panics for an item, its result is the zero value.
+func (s *Slice[T]) MapConcurrent(fn func(T) (T, error)) (*Slice[T], []error) {
+	items := s.Snapshot()
+	results, errs := GoNOrdered(len(items), func(i int) (T, error) {
+		return fn(items[i])
+	})
+	return s.withItems(results), errs
+}
+
+// FilterConcurrent runs pred in a go routine for each item of a Snapshot and returns a new Slice
+// with the same capacity of the items for which pred returned true, like [GoFilter].
+func (s *Slice[T]) FilterConcurrent(pred func(T) (bool, error)) (*Slice[T], []error) {
+	kept, errs := GoFilter(s.Snapshot(), pred)
+	return s.withItems(kept), errs
+}
+
+// appendTo appends the items to dst.
+func (s *Slice[T]) appendTo(dst []T) []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.items.appendTo(dst)
+}
+
+// Range calls fn with each index and item until fn returns false.
+// It iterates over a Snapshot, so fn may modify the Slice
+// but will not see the modifications.
+func (s *Slice[T]) Range(fn func(i int, x T) bool) {
+	for i, x := range s.Snapshot() {
+		if !fn(i, x) {
+			return
+		}
+	}
+}
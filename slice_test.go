@@ -0,0 +1,191 @@
+package concurrent_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestSlice(t *testing.T) {
+	var s concurrent.Slice[int]
+	must.Eq(t, 0, s.Len())
+	_, ok := s.Shift()
+	must.False(t, ok)
+
+	errs := concurrent.GoN(10, func(i int) error {
+		s.Append(i)
+		return nil
+	})
+	must.Nil(t, errs)
+	must.Eq(t, 10, s.Len())
+	items := s.TakeAll()
+	must.SliceContainsAll(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, items)
+	must.Eq(t, 0, s.Len())
+
+	s.Append(1, 2, 3)
+	x, ok := s.Shift()
+	must.True(t, ok)
+	must.Eq(t, 1, x)
+	must.Eq(t, []int{2, 3}, s.Snapshot())
+}
+
+func TestSliceSetDeleteRange(t *testing.T) {
+	s := concurrent.NewSlice(1, 2, 3, 4)
+	x, ok := s.Get(1)
+	must.True(t, ok)
+	must.Eq(t, 2, x)
+	_, ok = s.Get(4)
+	must.False(t, ok)
+
+	must.True(t, s.Set(0, 10))
+	must.False(t, s.Set(-1, 10))
+	must.True(t, s.Delete(2))
+	must.False(t, s.Delete(3))
+	snapshot := s.Snapshot()
+	must.Eq(t, []int{10, 2, 4}, snapshot)
+	snapshot[0] = 0
+	must.Eq(t, []int{10, 2, 4}, s.Snapshot())
+
+	var ranged []int
+	s.Range(func(i int, x int) bool {
+		s.Append(x)
+		ranged = append(ranged, i, x)
+		return i < 1
+	})
+	must.Eq(t, []int{0, 10, 1, 2}, ranged)
+	must.Eq(t, []int{10, 2, 4, 10, 2}, s.Snapshot())
+}
+
+func TestSliceInsertRemoveAt(t *testing.T) {
+	var s concurrent.Slice[string]
+	must.False(t, s.Insert(1, "a"))
+	must.True(t, s.Insert(0, "b"))
+	must.True(t, s.Insert(0, "a"))
+	must.True(t, s.Insert(2, "d"))
+	must.True(t, s.Insert(2, "c"))
+	must.Eq(t, []string{"a", "b", "c", "d"}, s.Snapshot())
+
+	x, ok := s.RemoveAt(1)
+	must.True(t, ok)
+	must.Eq(t, "b", x)
+	_, ok = s.RemoveAt(3)
+	must.False(t, ok)
+	must.Eq(t, []string{"a", "c", "d"}, s.Snapshot())
+}
+
+func TestSliceSearchSort(t *testing.T) {
+	s := concurrent.NewSlice(3, 1, 4, 1, 5)
+	isOne := func(x int) bool { return x == 1 }
+	isTwo := func(x int) bool { return x == 2 }
+	must.True(t, s.Contains(isOne))
+	must.False(t, s.Contains(isTwo))
+	must.Eq(t, 1, s.IndexFunc(isOne))
+	must.Eq(t, -1, s.IndexFunc(isTwo))
+
+	s.Sort(func(a, b int) bool { return a < b })
+	must.Eq(t, []int{1, 1, 3, 4, 5}, s.Snapshot())
+	must.Eq(t, 0, s.IndexFunc(isOne))
+}
+
+func TestSliceCap(t *testing.T) {
+	oldest := concurrent.NewSliceCap[int](3, concurrent.DropOldest)
+	oldest.Append(1, 2)
+	oldest.Append(3, 4)
+	must.Eq(t, []int{2, 3, 4}, oldest.Snapshot())
+	oldest.Append(5, 6, 7, 8)
+	must.Eq(t, []int{6, 7, 8}, oldest.Snapshot())
+	must.True(t, oldest.Insert(2, 0))
+	must.Eq(t, []int{7, 0, 8}, oldest.Snapshot())
+
+	newest := concurrent.NewSliceCap[int](3, concurrent.DropNewest)
+	newest.Append(1, 2)
+	newest.Append(3, 4)
+	must.Eq(t, []int{1, 2, 3}, newest.Snapshot())
+	newest.Append(5)
+	must.False(t, newest.Insert(0, 0))
+	must.Eq(t, []int{1, 2, 3}, newest.Snapshot())
+	newest.Shift()
+	must.True(t, newest.Insert(0, 0))
+	must.Eq(t, []int{0, 2, 3}, newest.Snapshot())
+}
+
+func TestSliceCapAllocs(t *testing.T) {
+	s := concurrent.NewSliceCap[int](100, concurrent.DropOldest)
+	for i := range 100 {
+		s.Append(i)
+	}
+	allocs := testing.AllocsPerRun(1000, func() {
+		s.Append(1)
+		s.Append(2, 3)
+	})
+	must.Eq(t, 0.0, allocs)
+	must.Eq(t, 100, s.Len())
+}
+
+func TestSliceCloneCopyTo(t *testing.T) {
+	s := concurrent.NewSliceCap[int](3, concurrent.DropOldest)
+	s.Append(1, 2)
+	clone := s.Clone()
+	s.Append(3)
+	must.Eq(t, []int{1, 2}, clone.Snapshot())
+	clone.Append(4, 5)
+	must.Eq(t, []int{2, 4, 5}, clone.Snapshot())
+	must.Eq(t, []int{1, 2, 3}, s.Snapshot())
+
+	dst := make([]int, 2)
+	must.Eq(t, 2, s.CopyTo(dst))
+	must.Eq(t, []int{1, 2}, dst)
+	dst = make([]int, 5)
+	must.Eq(t, 3, s.CopyTo(dst))
+	must.Eq(t, []int{1, 2, 3, 0, 0}, dst)
+}
+
+func TestSliceMapFilterConcurrent(t *testing.T) {
+	errOdd := errors.New("odd")
+	s := concurrent.NewSlice(1, 2, 3, 4)
+	doubled, errs := s.MapConcurrent(func(x int) (int, error) {
+		return x * 2, nil
+	})
+	must.Nil(t, errs)
+	must.Eq(t, []int{2, 4, 6, 8}, doubled.Snapshot())
+	must.Eq(t, []int{1, 2, 3, 4}, s.Snapshot())
+
+	halved, errs := doubled.MapConcurrent(func(x int) (int, error) {
+		if x == 6 {
+			return 0, errOdd
+		}
+		return x / 2, nil
+	})
+	must.Eq(t, []error{errOdd}, errs)
+	must.Eq(t, []int{1, 2, 0, 4}, halved.Snapshot())
+
+	even, errs := s.FilterConcurrent(func(x int) (bool, error) {
+		if x == 3 {
+			panic("three")
+		}
+		return x%2 == 0, nil
+	})
+	must.SliceLen(t, 1, errs)
+	must.Eq(t, []int{2, 4}, even.Snapshot())
+}
+
+func TestSliceShiftWrap(t *testing.T) {
+	s := concurrent.NewSlice(1, 2, 3, 4)
+	for i := 5; i <= 8; i++ {
+		x, ok := s.Shift()
+		must.True(t, ok)
+		must.Eq(t, i-4, x)
+		s.Append(i)
+	}
+	must.Eq(t, []int{5, 6, 7, 8}, s.Snapshot())
+	must.True(t, s.Insert(1, 0))
+	must.True(t, s.Delete(3))
+	must.Eq(t, []int{5, 0, 6, 8}, s.Snapshot())
+	s.Sort(func(a, b int) bool { return a < b })
+	must.Eq(t, []int{0, 5, 6, 8}, s.Snapshot())
+	dst := make([]int, 3)
+	must.Eq(t, 3, s.CopyTo(dst))
+	must.Eq(t, []int{0, 5, 6}, dst)
+}
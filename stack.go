@@ -0,0 +1,82 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+)
+
+// Stack is a last in, first out stack that is safe to use from multiple go routines.
+//
+// The zero value is an empty Stack ready to use.
+// A Stack must not be copied after first use.
+type Stack[T any] struct {
+	mu    sync.Mutex
+	items []T
+	// wake is closed to wake up the go routines waiting in Pop.
+	// It is nil when none are waiting.
+	wake chan struct{}
+}
+
+// Push adds items to the top of the Stack.
+// The last item given is the first that will be popped.
+func (s *Stack[T]) Push(items ...T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = append(s.items, items...)
+	if s.wake != nil && len(s.items) > 0 {
+		close(s.wake)
+		s.wake = nil
+	}
+}
+
+// TryPop removes the top item without blocking.
+// It returns false if the Stack is empty.
+func (s *Stack[T]) TryPop() (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pop()
+}
+
+// Pop removes the top item, waiting for one to be pushed if the Stack is empty.
+// It returns the cause of ctx if ctx is done before an item is pushed.
+func (s *Stack[T]) Pop(ctx context.Context) (T, error) {
+	for {
+		s.mu.Lock()
+		if x, ok := s.pop(); ok {
+			s.mu.Unlock()
+			return x, nil
+		}
+		if s.wake == nil {
+			s.wake = make(chan struct{})
+		}
+		wake := s.wake
+		s.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			var zero T
+			return zero, context.Cause(ctx)
+		}
+	}
+}
+
+// Len returns the number of items in the Stack.
+func (s *Stack[T]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// pop must be called with the lock held.
+func (s *Stack[T]) pop() (T, bool) {
+	var zero T
+	if len(s.items) == 0 {
+		return zero, false
+	}
+	last := len(s.items) - 1
+	x := s.items[last]
+	s.items[last] = zero
+	s.items = s.items[:last]
+	return x, true
+}
@@ -0,0 +1,53 @@
+package concurrent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestStack(t *testing.T) {
+	var s concurrent.Stack[int]
+	_, ok := s.TryPop()
+	must.False(t, ok)
+	s.Push(1, 2)
+	s.Push(3)
+	must.Eq(t, 3, s.Len())
+	for _, want := range []int{3, 2, 1} {
+		x, ok := s.TryPop()
+		must.True(t, ok)
+		must.Eq(t, want, x)
+	}
+	must.Eq(t, 0, s.Len())
+
+	errs := concurrent.GoN(100, func(i int) error {
+		s.Push(i)
+		return nil
+	})
+	must.Nil(t, errs)
+	errs = concurrent.GoN(100, func(int) error {
+		_, err := s.Pop(context.Background())
+		return err
+	})
+	must.Nil(t, errs)
+	must.Eq(t, 0, s.Len())
+}
+
+func TestStackPop(t *testing.T) {
+	var s concurrent.Stack[int]
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err := s.Pop(ctx)
+	must.ErrorIs(t, err, context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(time.Millisecond)
+		s.Push(1)
+	}()
+	x, err := s.Pop(context.Background())
+	must.NoError(t, err)
+	must.Eq(t, 1, x)
+}
@@ -0,0 +1,546 @@
+package concurrent
+
+import (
+	"context"
+	"iter"
+	"sync"
+
+	"github.com/gregwebs/errors"
+)
+
+// ErrClosed is returned when receiving from an [UnboundedChan] that is closed and empty,
+// or when sending with SendWait to an UnboundedChan that is closed.
+// [RecvContext] also returns it for a channel that is closed.
+var ErrClosed = errors.New("concurrent: channel is closed")
+
+// UnboundedChan is a queue whose Send never blocks.
+// Receive items in order with Recv or RecvWait.
+// Close the channel and retrieve all the remaining items with Drain()
+//
+// A capacity can be given with [NewBoundedChan].
+//
+// Copies of an UnboundedChan share the same queue.
+// Must be constructed with [NewUnboundedChan] or [NewBoundedChan]
+type UnboundedChan[T any] struct {
+	q *unboundedQueue[T]
+}
+
+type unboundedQueue[T any] struct {
+	mu    sync.Mutex
+	items ringBuffer[T]
+	// urgent holds the items sent by SendPriority, which are received before items.
+	// urgent[p-1] is the lane of priority p.
+	urgent []ringBuffer[T]
+	closed bool
+	// wake is closed to wake up the receivers waiting in RecvWait
+	wake    chan struct{}
+	waiting bool
+
+	// capacity is 0 when unbounded
+	capacity int
+	policy   OverflowPolicy
+	// wakeSpace is closed when items are removed to wake up the senders waiting for space and WaitEmpty
+	wakeSpace    chan struct{}
+	waitingSpace bool
+
+	stats UnboundedChanStats
+
+	// the watermarks are disabled when notifyWatermark is nil
+	highWatermark   int
+	lowWatermark    int
+	notifyWatermark func(WatermarkState)
+	aboveWatermark  bool
+
+	// events holds the callbacks to call once the lock is released by unlock
+	events []queueEvent[T]
+	// delivering is set while a go routine is calling the callbacks of events
+	delivering bool
+
+	onDrop func(T)
+	// dropClosed makes Send discard items after Drain instead of panicking
+	dropClosed bool
+}
+
+// WatermarkState is given to the function set by [UnboundedChan.SetWatermarks] when the depth of the queue crosses a watermark.
+type WatermarkState int
+
+const (
+	// WatermarkLow means that the depth fell to the low watermark after reaching the high watermark.
+	// Producers can resume at their normal rate.
+	WatermarkLow WatermarkState = iota
+	// WatermarkHigh means that the depth reached the high watermark.
+	// Producers should slow down.
+	WatermarkHigh
+)
+
+// UnboundedChanStats is a snapshot of the health of an [UnboundedChan] returned by Stats.
+// The counts are totals since the UnboundedChan was created.
+type UnboundedChanStats struct {
+	// Depth is the number of items in the queue.
+	Depth int
+	// MaxDepth is the largest Depth the queue has had.
+	MaxDepth int
+	// Sent is the number of items added to the queue.
+	Sent int
+	// Received is the number of items removed from the queue by receiving or draining.
+	Received int
+	// Dropped is the number of items discarded by the [DropOldest] or [DropNewest] policy
+	// or sent after the UnboundedChan was closed with [UnboundedChan.SetDropClosed].
+	Dropped int
+}
+
+// OverflowPolicy decides what Send does when an UnboundedChan created by [NewBoundedChan] is full.
+type OverflowPolicy int
+
+const (
+	// Block makes Send wait until an item is received.
+	Block OverflowPolicy = iota
+	// DropOldest removes the oldest item to make room for the new item.
+	DropOldest
+	// DropNewest discards the new item.
+	DropNewest
+)
+
+// NewUnboundedChan create an UnboundedChan
+func NewUnboundedChan[T any]() UnboundedChan[T] {
+	return UnboundedChan[T]{q: &unboundedQueue[T]{
+		wake:      make(chan struct{}),
+		wakeSpace: make(chan struct{}),
+	}}
+}
+
+// NewBoundedChan creates an UnboundedChan that holds at most capacity items.
+// The policy decides what Send does when the queue is full.
+// capacity must be positive.
+func NewBoundedChan[T any](capacity int, policy OverflowPolicy) UnboundedChan[T] {
+	if capacity < 1 {
+		panic("concurrent: capacity must be positive")
+	}
+	return UnboundedChan[T]{q: &unboundedQueue[T]{
+		wake:      make(chan struct{}),
+		capacity:  capacity,
+		policy:    policy,
+		wakeSpace: make(chan struct{}),
+	}}
+}
+
+// Send adds an item to the queue.
+// Like a channel, it panics if the UnboundedChan has been closed by Drain,
+// unless [UnboundedChan.SetDropClosed] is used.
+//
+// If the UnboundedChan was created by [NewBoundedChan] and is full, what happens depends on the [OverflowPolicy].
+func (uc UnboundedChan[T]) Send(x T) {
+	q := uc.q
+	q.mu.Lock()
+	defer q.unlock()
+	q.mustSend(x, 0)
+}
+
+// SendWait is the same as Send but gives up if ctx is done while waiting for space with the [Block] policy.
+// It returns the cause of ctx in that case, or [ErrClosed] if the UnboundedChan has been closed by Drain.
+// This gives producers backpressure that they can cancel.
+func (uc UnboundedChan[T]) SendWait(ctx context.Context, x T) error {
+	q := uc.q
+	q.mu.Lock()
+	defer q.unlock()
+	return q.send(ctx, x, 0)
+}
+
+// SendPriority is the same as Send but the item is received before all the items sent with a lower priority,
+// for example to deliver control messages ahead of bulk data.
+// Items sent without priority with Send have a priority of 0.
+// Items of the same priority are received in the order they were sent.
+// priority must be positive.
+//
+// The [DropOldest] policy drops the items of the lowest priority first.
+func (uc UnboundedChan[T]) SendPriority(x T, priority int) {
+	if priority < 1 {
+		panic("concurrent: priority must be positive")
+	}
+	q := uc.q
+	q.mu.Lock()
+	defer q.unlock()
+	q.mustSend(x, priority)
+}
+
+// SendAll adds all the items to the queue in order while acquiring the lock only once.
+// It is the same as calling Send for each item.
+func (uc UnboundedChan[T]) SendAll(items []T) {
+	q := uc.q
+	q.mu.Lock()
+	defer q.unlock()
+	for _, x := range items {
+		q.mustSend(x, 0)
+	}
+}
+
+// mustSend panics if the UnboundedChan is closed unless dropClosed is set.
+// It must be called with the lock held.
+func (q *unboundedQueue[T]) mustSend(x T, priority int) {
+	if err := q.send(context.Background(), x, priority); err != nil {
+		if q.dropClosed {
+			q.drop(x)
+			return
+		}
+		panic("concurrent: send on closed UnboundedChan")
+	}
+}
+
+// send pushes x to the lane of priority, which is items for a priority of 0.
+// It must be called with the lock held.
+// With the Block policy the lock is released while waiting for space.
+func (q *unboundedQueue[T]) send(ctx context.Context, x T, priority int) error {
+	for {
+		if q.closed {
+			return ErrClosed
+		}
+		if q.capacity == 0 || q.len() < q.capacity {
+			break
+		}
+		switch q.policy {
+		case DropNewest:
+			q.drop(x)
+			return nil
+		case DropOldest:
+			oldest, ok := q.items.pop()
+			for p := 0; !ok && p < len(q.urgent); p++ {
+				oldest, ok = q.urgent[p].pop()
+			}
+			q.drop(oldest)
+		default:
+			q.waitingSpace = true
+			wakeSpace := q.wakeSpace
+			q.mu.Unlock()
+			select {
+			case <-wakeSpace:
+			case <-ctx.Done():
+				q.mu.Lock()
+				return context.Cause(ctx)
+			}
+			q.mu.Lock()
+		}
+	}
+	if priority == 0 {
+		q.items.push(x)
+	} else {
+		if priority > len(q.urgent) {
+			q.urgent = append(q.urgent, make([]ringBuffer[T], priority-len(q.urgent))...)
+		}
+		q.urgent[priority-1].push(x)
+	}
+	q.stats.Sent++
+	q.stats.MaxDepth = max(q.stats.MaxDepth, q.len())
+	q.checkWatermarks()
+	q.notify()
+	return nil
+}
+
+// Recv removes the oldest item from the queue without blocking.
+// It returns false if the queue is empty.
+func (uc UnboundedChan[T]) Recv() (T, bool) {
+	q := uc.q
+	q.mu.Lock()
+	defer q.unlock()
+	return q.recv()
+}
+
+// RecvN removes up to max of the oldest items from the queue without blocking.
+// It returns nil if the queue is empty.
+func (uc UnboundedChan[T]) RecvN(max int) []T {
+	q := uc.q
+	q.mu.Lock()
+	defer q.unlock()
+	n := min(max, q.len())
+	if n <= 0 {
+		return nil
+	}
+	items := make([]T, n)
+	for i := range items {
+		items[i], _ = q.recv()
+	}
+	return items
+}
+
+// RecvWait removes the oldest item from the queue, waiting for one to be sent if the queue is empty.
+// It returns [ErrClosed] if the queue is empty and closed,
+// or the cause of ctx if ctx is done before an item is sent.
+//
+// Multiple go routines can wait in RecvWait: each item is received by exactly one of them,
+// although which one is not specified, so the distribution between receivers is not guaranteed to be fair.
+// An item is only removed when it is returned, so a receiver that gives up because ctx is done does not lose an item.
+func (uc UnboundedChan[T]) RecvWait(ctx context.Context) (T, error) {
+	q := uc.q
+	for {
+		q.mu.Lock()
+		if x, ok := q.recv(); ok {
+			q.unlock()
+			return x, nil
+		}
+		if q.closed {
+			q.mu.Unlock()
+			var zero T
+			return zero, ErrClosed
+		}
+		q.waiting = true
+		wake := q.wake
+		q.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			var zero T
+			return zero, context.Cause(ctx)
+		}
+	}
+}
+
+// WaitEmpty waits until all the items in the queue have been received or drained.
+// It returns the cause of ctx if ctx is done first.
+func (uc UnboundedChan[T]) WaitEmpty(ctx context.Context) error {
+	q := uc.q
+	for {
+		q.mu.Lock()
+		if q.len() == 0 {
+			q.mu.Unlock()
+			return nil
+		}
+		q.waitingSpace = true
+		wakeSpace := q.wakeSpace
+		q.mu.Unlock()
+
+		select {
+		case <-wakeSpace:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+}
+
+// Seq returns an iterator that receives items with RecvWait until the UnboundedChan is closed and empty.
+//
+//	for x := range uc.Seq() {
+//
+// Drain closes the UnboundedChan but also takes the items that have not been received,
+// so the iterator stops without yielding them.
+func (uc UnboundedChan[T]) Seq() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for {
+			x, err := uc.RecvWait(context.Background())
+			if err != nil {
+				return
+			}
+			if !yield(x) {
+				return
+			}
+		}
+	}
+}
+
+// Drain closes the UnboundedChan and returns the items that have not been received.
+// It is safe to call more than once: later calls return no items.
+func (uc UnboundedChan[T]) Drain() []T {
+	return uc.DrainTo(nil)
+}
+
+// DrainTo is the same as Drain but appends the items to dst and returns the extended slice.
+// Passing dst[:0] reuses the buffer of a previous DrainTo.
+func (uc UnboundedChan[T]) DrainTo(dst []T) []T {
+	q := uc.q
+	q.mu.Lock()
+	defer q.unlock()
+	q.closed = true
+	q.stats.Received += q.len()
+	items := dst
+	for p := len(q.urgent) - 1; p >= 0; p-- {
+		items = q.urgent[p].appendTo(items)
+	}
+	items = q.items.appendTo(items)
+	q.urgent = nil
+	q.items = ringBuffer[T]{}
+	q.checkWatermarks()
+	q.notify()
+	q.notifySpace()
+	return items
+}
+
+// Stats returns the current depth and the counts of the items that went through the queue.
+func (uc UnboundedChan[T]) Stats() UnboundedChanStats {
+	q := uc.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats := q.stats
+	stats.Depth = q.len()
+	return stats
+}
+
+// SetWatermarks calls notify with [WatermarkHigh] when the depth of the queue reaches high,
+// and then with [WatermarkLow] when the depth falls to low.
+// This signals producers to slow down before the queue grows without limit.
+// Only the crossings are notified: notify is not called again until the other watermark is crossed.
+//
+// notify is called after the lock is released, so it may call the methods of the UnboundedChan.
+// The notifications are delivered one at a time in the order of the crossings,
+// which can mean that notify is called by a go routine other than the one that crossed the watermark.
+// A nil notify disables the watermarks.
+// low must not be greater than high.
+func (uc UnboundedChan[T]) SetWatermarks(high, low int, notify func(WatermarkState)) {
+	if low > high {
+		panic("concurrent: low watermark must not be greater than the high watermark")
+	}
+	q := uc.q
+	q.mu.Lock()
+	defer q.unlock()
+	q.highWatermark = high
+	q.lowWatermark = low
+	q.notifyWatermark = notify
+	q.aboveWatermark = false
+	q.checkWatermarks()
+}
+
+// SetOnDrop sets a function that is given the items discarded by the [DropOldest] or [DropNewest] policy,
+// for example to log them or to persist them elsewhere.
+// Together with [NewBoundedChan] this bounds the memory used while Send never blocks.
+//
+// fn is called after the lock is released, so it may call the methods of the UnboundedChan.
+// Like the notifications of [UnboundedChan.SetWatermarks], the items are given one at a time in the order they were dropped.
+func (uc UnboundedChan[T]) SetOnDrop(fn func(T)) {
+	q := uc.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.onDrop = fn
+}
+
+// SetDropClosed makes Send, SendAll, and SendPriority discard the items sent after the UnboundedChan is closed by Drain
+// instead of panicking.
+// The discarded items are counted as dropped and given to the function set by [UnboundedChan.SetOnDrop].
+// This avoids crashing in shutdown paths where producers race with Drain.
+// To get an error instead, use [UnboundedChan.SendWait], which returns [ErrClosed].
+func (uc UnboundedChan[T]) SetDropClosed(drop bool) {
+	q := uc.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dropClosed = drop
+}
+
+// drop must be called with the lock held.
+func (q *unboundedQueue[T]) drop(x T) {
+	q.stats.Dropped++
+	if q.onDrop != nil {
+		q.events = append(q.events, queueEvent[T]{onDrop: q.onDrop, dropped: x})
+	}
+}
+
+// checkWatermarks notifies when the depth crosses a watermark.
+// It must be called with the lock held after the depth changes.
+func (q *unboundedQueue[T]) checkWatermarks() {
+	if q.notifyWatermark == nil {
+		return
+	}
+	depth := q.len()
+	if !q.aboveWatermark && depth >= q.highWatermark {
+		q.aboveWatermark = true
+		q.events = append(q.events, queueEvent[T]{notifyWatermark: q.notifyWatermark, state: WatermarkHigh})
+	} else if q.aboveWatermark && depth <= q.lowWatermark {
+		q.aboveWatermark = false
+		q.events = append(q.events, queueEvent[T]{notifyWatermark: q.notifyWatermark, state: WatermarkLow})
+	}
+}
+
+// queueEvent is a callback call that waits for the lock to be released.
+// It is either a dropped item when onDrop is set or a watermark crossing.
+type queueEvent[T any] struct {
+	onDrop          func(T)
+	dropped         T
+	notifyWatermark func(WatermarkState)
+	state           WatermarkState
+}
+
+func (e queueEvent[T]) deliver() {
+	if e.onDrop != nil {
+		e.onDrop(e.dropped)
+	} else {
+		e.notifyWatermark(e.state)
+	}
+}
+
+// unlock releases the lock and then calls the callbacks of the events in order.
+// Only one go routine delivers at a time so that the callbacks are not called concurrently or out of order.
+// A callback that uses the UnboundedChan adds its events to be delivered after it returns.
+func (q *unboundedQueue[T]) unlock() {
+	if q.delivering || len(q.events) == 0 {
+		q.mu.Unlock()
+		return
+	}
+	q.delivering = true
+	defer func() {
+		q.delivering = false
+		q.mu.Unlock()
+	}()
+	for len(q.events) > 0 {
+		events := q.events
+		q.events = nil
+		func() {
+			q.mu.Unlock()
+			// relock even if a callback panics so that the deferred reset of delivering holds the lock
+			defer q.mu.Lock()
+			for _, e := range events {
+				e.deliver()
+			}
+		}()
+	}
+}
+
+// recv must be called with the lock held.
+func (q *unboundedQueue[T]) recv() (T, bool) {
+	x, ok := q.pop()
+	if ok {
+		q.stats.Received++
+	}
+	return x, ok
+}
+
+// len must be called with the lock held.
+func (q *unboundedQueue[T]) len() int {
+	n := q.items.len()
+	for p := range q.urgent {
+		n += q.urgent[p].len()
+	}
+	return n
+}
+
+// pop must be called with the lock held.
+func (q *unboundedQueue[T]) pop() (T, bool) {
+	var x T
+	ok := false
+	for p := len(q.urgent) - 1; !ok && p >= 0; p-- {
+		x, ok = q.urgent[p].pop()
+	}
+	if !ok {
+		x, ok = q.items.pop()
+	}
+	if ok {
+		q.checkWatermarks()
+		q.notifySpace()
+	}
+	return x, ok
+}
+
+// notify wakes up the receivers waiting in RecvWait.
+// It must be called with the lock held.
+func (q *unboundedQueue[T]) notify() {
+	if q.waiting {
+		close(q.wake)
+		q.wake = make(chan struct{})
+		q.waiting = false
+	}
+}
+
+// notifySpace wakes up the senders waiting for space and WaitEmpty.
+// It must be called with the lock held.
+func (q *unboundedQueue[T]) notifySpace() {
+	if q.waitingSpace {
+		close(q.wakeSpace)
+		q.wakeSpace = make(chan struct{})
+		q.waitingSpace = false
+	}
+}
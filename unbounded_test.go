@@ -0,0 +1,401 @@
+package concurrent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gregwebs/go-concurrent"
+	"github.com/shoenig/test/must"
+)
+
+func TestUnboundedChan(t *testing.T) {
+	uc := concurrent.NewUnboundedChan[int]()
+	for i := 0; i < 20; i++ {
+		uc.Send(i)
+	}
+	drained := uc.Drain()
+	must.Len(t, 20, drained)
+	must.Eq(t, 0, drained[0])
+	must.Eq(t, 19, drained[19])
+}
+
+func TestUnboundedChanRecv(t *testing.T) {
+	uc := concurrent.NewUnboundedChan[int]()
+	_, ok := uc.Recv()
+	must.False(t, ok)
+	uc.Send(1)
+	uc.Send(2)
+	x, ok := uc.Recv()
+	must.True(t, ok)
+	must.Eq(t, 1, x)
+	must.Eq(t, []int{2}, uc.Drain())
+}
+
+func TestUnboundedChanRecvWait(t *testing.T) {
+	ctx := context.Background()
+	uc := concurrent.NewUnboundedChan[int]()
+	uc.Send(1)
+	x, err := uc.RecvWait(ctx)
+	must.NoError(t, err)
+	must.Eq(t, 1, x)
+
+	go func() {
+		time.Sleep(time.Millisecond)
+		uc.Send(2)
+	}()
+	x, err = uc.RecvWait(ctx)
+	must.NoError(t, err)
+	must.Eq(t, 2, x)
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	_, err = uc.RecvWait(ctxTimeout)
+	must.ErrorIs(t, err, context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(time.Millisecond)
+		uc.Drain()
+	}()
+	_, err = uc.RecvWait(ctx)
+	must.ErrorIs(t, err, concurrent.ErrClosed)
+}
+
+func TestBoundedChan(t *testing.T) {
+	oldest := concurrent.NewBoundedChan[int](2, concurrent.DropOldest)
+	for i := 1; i <= 4; i++ {
+		oldest.Send(i)
+	}
+	must.Eq(t, []int{3, 4}, oldest.Drain())
+
+	newest := concurrent.NewBoundedChan[int](2, concurrent.DropNewest)
+	for i := 1; i <= 4; i++ {
+		newest.Send(i)
+	}
+	must.Eq(t, []int{1, 2}, newest.Drain())
+
+	block := concurrent.NewBoundedChan[int](2, concurrent.Block)
+	block.Send(1)
+	block.Send(2)
+	sent := make(chan struct{})
+	go func() {
+		block.Send(3)
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		t.Fatal("Send should block when full")
+	case <-time.After(5 * time.Millisecond):
+	}
+	x, ok := block.Recv()
+	must.True(t, ok)
+	must.Eq(t, 1, x)
+	<-sent
+	must.Eq(t, []int{2, 3}, block.Drain())
+}
+
+func TestBoundedChanSendWait(t *testing.T) {
+	block := concurrent.NewBoundedChan[int](1, concurrent.Block)
+	must.NoError(t, block.SendWait(context.Background(), 1))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	must.ErrorIs(t, block.SendWait(ctx, 2), context.DeadlineExceeded)
+
+	errs := make(chan error)
+	go func() {
+		errs <- block.SendWait(context.Background(), 3)
+	}()
+	x, err := block.RecvWait(context.Background())
+	must.NoError(t, err)
+	must.Eq(t, 1, x)
+	must.NoError(t, <-errs)
+
+	go func() {
+		errs <- block.SendWait(context.Background(), 4)
+	}()
+	time.Sleep(time.Millisecond)
+	must.Eq(t, []int{3}, block.Drain())
+	must.ErrorIs(t, <-errs, concurrent.ErrClosed)
+	must.ErrorIs(t, block.SendWait(context.Background(), 5), concurrent.ErrClosed)
+}
+
+func TestUnboundedChanSendAll(t *testing.T) {
+	uc := concurrent.NewUnboundedChan[int]()
+	uc.Send(0)
+	uc.SendAll([]int{1, 2, 3})
+	uc.SendAll(nil)
+	must.Eq(t, []int{0, 1, 2, 3}, uc.Drain())
+
+	bounded := concurrent.NewBoundedChan[int](2, concurrent.DropOldest)
+	bounded.SendAll([]int{1, 2, 3})
+	must.Eq(t, []int{2, 3}, bounded.Drain())
+}
+
+func TestUnboundedChanRecvN(t *testing.T) {
+	uc := concurrent.NewUnboundedChan[int]()
+	must.Nil(t, uc.RecvN(3))
+	uc.SendAll([]int{1, 2, 3, 4, 5})
+	must.Eq(t, []int{1, 2, 3}, uc.RecvN(3))
+	must.Eq(t, []int{4, 5}, uc.RecvN(3))
+	must.Nil(t, uc.RecvN(3))
+	uc.Send(6)
+	must.Nil(t, uc.RecvN(0))
+	must.Eq(t, []int{6}, uc.Drain())
+}
+
+func TestUnboundedChanSeq(t *testing.T) {
+	uc := concurrent.NewUnboundedChan[int]()
+	uc.SendAll([]int{1, 2, 3})
+	var got []int
+	for x := range uc.Seq() {
+		got = append(got, x)
+		if x == 2 {
+			break
+		}
+	}
+	must.Eq(t, []int{1, 2}, got)
+
+	received := make(chan int)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for x := range uc.Seq() {
+			received <- x
+		}
+	}()
+	must.Eq(t, 3, <-received)
+	uc.Send(4)
+	must.Eq(t, 4, <-received)
+	must.SliceEmpty(t, uc.Drain())
+	<-done
+}
+
+func TestUnboundedChanDrainTo(t *testing.T) {
+	buf := make([]int, 0, 10)
+	uc := concurrent.NewUnboundedChan[int]()
+	uc.SendAll([]int{1, 2, 3})
+	buf = uc.DrainTo(buf)
+	must.Eq(t, []int{1, 2, 3}, buf)
+
+	uc = concurrent.NewUnboundedChan[int]()
+	uc.SendAll([]int{4, 5})
+	reused := uc.DrainTo(buf[:0])
+	must.Eq(t, []int{4, 5}, reused)
+	must.Eq(t, &buf[0], &reused[0])
+
+	uc = concurrent.NewUnboundedChan[int]()
+	uc.Send(6)
+	must.Eq(t, []int{4, 5, 6}, uc.DrainTo(reused))
+	must.Eq(t, []int{4, 5}, uc.DrainTo(reused))
+}
+
+func TestUnboundedChanWaitEmpty(t *testing.T) {
+	uc := concurrent.NewUnboundedChan[int]()
+	must.NoError(t, uc.WaitEmpty(context.Background()))
+
+	uc.SendAll([]int{1, 2, 3})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	must.ErrorIs(t, uc.WaitEmpty(ctx), context.DeadlineExceeded)
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			time.Sleep(time.Millisecond)
+			uc.Recv()
+		}
+	}()
+	must.NoError(t, uc.WaitEmpty(context.Background()))
+	_, ok := uc.Recv()
+	must.False(t, ok)
+
+	bc := concurrent.NewBoundedChan[int](2, concurrent.Block)
+	bc.SendAll([]int{1, 2})
+	go bc.Drain()
+	must.NoError(t, bc.WaitEmpty(context.Background()))
+}
+
+func TestUnboundedChanStats(t *testing.T) {
+	uc := concurrent.NewUnboundedChan[int]()
+	must.Eq(t, concurrent.UnboundedChanStats{}, uc.Stats())
+	uc.SendAll([]int{1, 2, 3})
+	uc.Recv()
+	uc.Send(4)
+	must.Eq(t, concurrent.UnboundedChanStats{Depth: 3, MaxDepth: 3, Sent: 4, Received: 1}, uc.Stats())
+	uc.RecvN(2)
+	uc.Drain()
+	must.Eq(t, concurrent.UnboundedChanStats{Depth: 0, MaxDepth: 3, Sent: 4, Received: 4}, uc.Stats())
+
+	oldest := concurrent.NewBoundedChan[int](2, concurrent.DropOldest)
+	oldest.SendAll([]int{1, 2, 3, 4})
+	must.Eq(t, concurrent.UnboundedChanStats{Depth: 2, MaxDepth: 2, Sent: 4, Dropped: 2}, oldest.Stats())
+
+	newest := concurrent.NewBoundedChan[int](2, concurrent.DropNewest)
+	newest.SendAll([]int{1, 2, 3, 4})
+	must.Eq(t, concurrent.UnboundedChanStats{Depth: 2, MaxDepth: 2, Sent: 2, Dropped: 2}, newest.Stats())
+}
+
+func TestUnboundedChanSendPriority(t *testing.T) {
+	uc := concurrent.NewUnboundedChan[string]()
+	uc.SendAll([]string{"bulk1", "bulk2"})
+	uc.SendPriority("control1", 1)
+	uc.SendPriority("control2", 1)
+	x, ok := uc.Recv()
+	must.True(t, ok)
+	must.Eq(t, "control1", x)
+	uc.Send("bulk3")
+	must.Eq(t, []string{"control2", "bulk1", "bulk2", "bulk3"}, uc.Drain())
+
+	bc := concurrent.NewBoundedChan[string](2, concurrent.DropOldest)
+	bc.SendPriority("control1", 1)
+	bc.Send("bulk1")
+	bc.Send("bulk2")
+	must.Eq(t, []string{"control1", "bulk2"}, bc.RecvN(2))
+	bc.SendPriority("control2", 1)
+	bc.SendPriority("control3", 1)
+	bc.SendPriority("control4", 1)
+	must.Eq(t, concurrent.UnboundedChanStats{Depth: 2, MaxDepth: 2, Sent: 6, Received: 2, Dropped: 2}, bc.Stats())
+	must.Eq(t, []string{"control3", "control4"}, bc.Drain())
+
+	// higher priorities are received first and dropped last
+	levels := concurrent.NewBoundedChan[string](3, concurrent.DropOldest)
+	levels.Send("bulk")
+	levels.SendPriority("low", 1)
+	levels.SendPriority("high", 3)
+	levels.SendPriority("mid", 2)
+	must.Eq(t, []string{"high", "mid", "low"}, levels.Drain())
+	levels = concurrent.NewBoundedChan[string](2, concurrent.DropOldest)
+	levels.SendPriority("high", 2)
+	levels.SendPriority("low1", 1)
+	levels.SendPriority("low2", 1)
+	must.Eq(t, []string{"high", "low2"}, levels.Drain())
+
+	err := concurrent.Recovered(func() error { uc.SendPriority("zero", 0); return nil })
+	must.Error(t, err)
+}
+
+// The queue wraps around, grows, and shrinks while keeping the order
+func TestUnboundedChanOrder(t *testing.T) {
+	uc := concurrent.NewUnboundedChan[int]()
+	sent, received := 0, 0
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 3000; i++ {
+			uc.Send(sent)
+			sent++
+			if i%3 == 0 {
+				x, ok := uc.Recv()
+				must.True(t, ok)
+				must.Eq(t, received, x)
+				received++
+			}
+		}
+		for _, x := range uc.RecvN(sent - received - 7) {
+			must.Eq(t, received, x)
+			received++
+		}
+	}
+	rest := uc.Drain()
+	must.SliceLen(t, 7, rest)
+	for _, x := range rest {
+		must.Eq(t, received, x)
+		received++
+	}
+	must.Eq(t, sent, received)
+}
+
+// A long lived queue that always has a backlog
+func TestUnboundedChanSetWatermarks(t *testing.T) {
+	uc := concurrent.NewUnboundedChan[int]()
+	var states []concurrent.WatermarkState
+	var depths []int
+	uc.SetWatermarks(3, 1, func(state concurrent.WatermarkState) {
+		states = append(states, state)
+		// notify is called without the lock held, so it can use the UnboundedChan
+		depths = append(depths, uc.Stats().Depth)
+	})
+	uc.SendAll([]int{1, 2})
+	must.SliceEmpty(t, states)
+	uc.SendAll([]int{3, 4})
+	must.Eq(t, []concurrent.WatermarkState{concurrent.WatermarkHigh}, states)
+	uc.RecvN(2)
+	must.Eq(t, []concurrent.WatermarkState{concurrent.WatermarkHigh}, states)
+	uc.Recv()
+	must.Eq(t, []concurrent.WatermarkState{concurrent.WatermarkHigh, concurrent.WatermarkLow}, states)
+	uc.SendAll([]int{5, 6})
+	uc.Drain()
+	must.Eq(t, []concurrent.WatermarkState{concurrent.WatermarkHigh, concurrent.WatermarkLow, concurrent.WatermarkHigh, concurrent.WatermarkLow}, states)
+	must.Eq(t, []int{4, 1, 3, 0}, depths)
+}
+
+func TestUnboundedChanSetOnDrop(t *testing.T) {
+	for _, test := range []struct {
+		policy   concurrent.OverflowPolicy
+		kept     []int
+		expected []int
+	}{
+		{concurrent.DropOldest, []int{3, 4}, []int{1, 2}},
+		{concurrent.DropNewest, []int{1, 2}, []int{3, 4}},
+	} {
+		bc := concurrent.NewBoundedChan[int](2, test.policy)
+		var dropped []int
+		var depths []int
+		bc.SetOnDrop(func(x int) {
+			dropped = append(dropped, x)
+			// fn is called without the lock held, so it can use the UnboundedChan
+			depths = append(depths, bc.Stats().Depth)
+		})
+		bc.SendAll([]int{1, 2, 3, 4})
+		must.Eq(t, test.expected, dropped)
+		must.Eq(t, []int{2, 2}, depths)
+		must.Eq(t, test.kept, bc.Drain())
+	}
+}
+
+func TestUnboundedChanSetDropClosed(t *testing.T) {
+	uc := concurrent.NewUnboundedChan[int]()
+	uc.Send(1)
+	must.Eq(t, []int{1}, uc.Drain())
+	must.SliceEmpty(t, uc.Drain())
+	err := concurrent.Recovered(func() error {
+		uc.Send(2)
+		return nil
+	})
+	must.Error(t, err)
+
+	var dropped []int
+	uc.SetOnDrop(func(x int) { dropped = append(dropped, x) })
+	uc.SetDropClosed(true)
+	uc.Send(3)
+	uc.SendAll([]int{4, 5})
+	uc.SendPriority(6, 1)
+	must.Eq(t, []int{3, 4, 5, 6}, dropped)
+	must.ErrorIs(t, uc.SendWait(context.Background(), 7), concurrent.ErrClosed)
+	must.Eq(t, concurrent.UnboundedChanStats{Sent: 1, Received: 1, MaxDepth: 1, Dropped: 4}, uc.Stats())
+}
+
+func BenchmarkUnboundedChanBacklog(b *testing.B) {
+	uc := concurrent.NewUnboundedChan[int]()
+	for i := 0; i < 1000; i++ {
+		uc.Send(i)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		uc.Send(i)
+		uc.Recv()
+	}
+}
+
+// A queue that fills up and then empties
+func BenchmarkUnboundedChanBursts(b *testing.B) {
+	uc := concurrent.NewUnboundedChan[int]()
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 100; j++ {
+			uc.Send(j)
+		}
+		for j := 0; j < 100; j++ {
+			uc.Recv()
+		}
+	}
+}